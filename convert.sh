@@ -50,8 +50,75 @@ if [[ -z ${scr} || ${scr} == "cpe" ]] && [[ -f cpe-dict.xml ]]; then
 	[[ ${gz} -eq 1 ]] && gzip -9 cpe-list.dat
 fi
 
-if [[ -z ${scr} || ${scr} == "cve" ]] && [[ -f cve-items.xml ]]; then
+if [[ -f cwe-catalog.xml ]]; then
+	cveopts="--cwe cwe-catalog.xml"
+fi
+
+if [[ -f cpe-aliases.dat.gz ]]; then
+	cveopts="${cveopts} --aliases cpe-aliases.dat.gz"
+elif [[ -f cpe-aliases.dat ]]; then
+	cveopts="${cveopts} --aliases cpe-aliases.dat"
+fi
+
+if [[ -f cpe-list.dat.gz ]]; then
+	cveopts="${cveopts} --cpe cpe-list.dat.gz"
+elif [[ -f cpe-list.dat ]]; then
+	cveopts="${cveopts} --cpe cpe-list.dat"
+fi
+
+if [[ -z ${scr} || ${scr} == "cve" ]] && ls nvdcve-2.0-*.json.gz &> /dev/null; then
+	rm -f cve-list.db3 cve-list.db3.bz2
+	go run cve2hs.go $@ ${cveopts} nvdcve-2.0-*.json.gz cve-list.db3
+elif [[ -z ${scr} || ${scr} == "cve" ]] && ls nvdcve-2.0-*.xml* &> /dev/null; then
 	rm -f cve-list.db3 cve-list.db3.bz2
-	go run cve2hs.go $@ cve-items.xml cve-list.db3
-	[[ ${gz} -eq 1 ]] && bzip2 -9 cve-list.db3
+	go run cve2hs.go $@ ${cveopts} nvdcve-2.0-*.xml* cve-list.db3
+fi
+
+if [[ ${scr} == "cveupd" ]] && [[ -f nvdcve-2.0-modified.json.gz ]]; then
+	if [[ $1 == "--json" ]]; then
+		go run cve2hs.go $@ ${cveopts} nvdcve-2.0-modified.json.gz nvdcve-2.0-recent.json.gz cve-changes.json
+	else
+		[[ -f cve-list.db3.bz2 ]] && bzip2 -d cve-list.db3.bz2
+		go run cve2hs.go $@ ${cveopts} --update nvdcve-2.0-modified.json.gz nvdcve-2.0-recent.json.gz cve-list.db3
+	fi
+fi
+
+if [[ -z ${scr} || ${scr} == "kev" ]] && [[ -f known-exploited.json ]]; then
+	if [[ $1 == "--json" ]]; then
+		go run kev2hs.go $@ known-exploited.json kev-list.json
+	else
+		[[ -f cve-list.db3.bz2 ]] && bzip2 -df cve-list.db3.bz2
+		go run kev2hs.go $@ known-exploited.json cve-list.db3
+	fi
+fi
+
+if [[ -z ${scr} || ${scr} == "epss" ]] && ls epss/epss_scores-*.csv.gz &> /dev/null; then
+	if [[ $1 == "--json" ]]; then
+		go run epss2hs.go $@ epss/epss_scores-*.csv.gz epss-list.json
+	else
+		[[ -f cve-list.db3.bz2 ]] && bzip2 -df cve-list.db3.bz2
+		go run epss2hs.go $@ epss/epss_scores-*.csv.gz cve-list.db3
+	fi
+fi
+
+if [[ -z ${scr} || ${scr} == "edb" ]] && [[ -f exploitdb.csv ]]; then
+	if [[ $1 == "--json" ]]; then
+		go run edb2hs.go $@ exploitdb.csv edb-list.json
+	else
+		[[ -f cve-list.db3.bz2 ]] && bzip2 -df cve-list.db3.bz2
+		go run edb2hs.go $@ exploitdb.csv cve-list.db3
+	fi
+fi
+
+if [[ -z ${scr} || ${scr} == "msf" ]] && [[ -f msf-modules.json ]]; then
+	if [[ $1 == "--json" ]]; then
+		go run msf2hs.go $@ msf-modules.json msf-list.json
+	else
+		[[ -f cve-list.db3.bz2 ]] && bzip2 -df cve-list.db3.bz2
+		go run msf2hs.go $@ msf-modules.json cve-list.db3
+	fi
+fi
+
+if [[ -z ${scr} || ${scr} == "cve" || ${scr} == "cveupd" || ${scr} == "kev" || ${scr} == "epss" || ${scr} == "edb" || ${scr} == "msf" ]] && [[ -f cve-list.db3 ]]; then
+	[[ ${gz} -eq 1 ]] && bzip2 -9f cve-list.db3
 fi
\ No newline at end of file
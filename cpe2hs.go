@@ -1,15 +1,15 @@
 package main
 
 import (
+	"io"
 	"os"
 	"bufio"
 	"regexp"
 	"strings"
 	"net/url"
-	"io/ioutil"
 	"encoding/xml"
-	"encoding/json"
-	"encoding/binary"
+
+	"github.com/RoliSoft/Host-Scanner-Scripts/hsformat"
 )
 
 var entries map[string]*entry
@@ -26,10 +26,20 @@ type subentry struct {
 	Tokens []string
 }
 
-// Reads the specified XML file and sends the entries for processing.
+type cpeItem struct {
+	Title []struct {
+		Name string `xml:",chardata"`
+		Lang string `xml:"lang,attr"`
+	} `xml:"title"`
+	Value string `xml:"name,attr"`
+}
+
+// Reads the specified XML file and sends the entries for processing. The
+// items are decoded one at a time, so the whole file is never held in memory.
 func parseInput(file string) error {
 	var err error
 	var fp  *os.File
+	var tok xml.Token
 
 	if fp, err = os.Open(file); err != nil {
 		return err
@@ -37,25 +47,31 @@ func parseInput(file string) error {
 
 	defer fp.Close()
 
-	txt, _ := ioutil.ReadAll(fp)
+	entries = make(map[string]*entry)
 
-	var lst struct {
-		Items []struct {
-			Title []struct {
-				Name string `xml:",chardata"`
-				Lang string `xml:"lang,attr"`
-			} `xml:"title"`
-			Value string `xml:"name,attr"`
-		} `xml:"cpe-item"`
-	}
+	dec := xml.NewDecoder(bufio.NewReader(fp))
 
-	if err = xml.Unmarshal(txt, &lst); err != nil {
-		return err
-	}
+	for {
+		if tok, err = dec.Token(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
 
-	entries = make(map[string]*entry)
+			break
+		}
+
+		elem, ok := tok.(xml.StartElement)
+
+		if !ok || elem.Name.Local != "cpe-item" {
+			continue
+		}
+
+		var cpe cpeItem
+
+		if err = dec.DecodeElement(&cpe, &elem); err != nil {
+			return err
+		}
 
-	for _, cpe := range lst.Items {
 		if len(cpe.Title) == 1 {
 			processEntry(cpe.Title[0].Name, cpe.Value)
 		} else {
@@ -67,6 +83,10 @@ func parseInput(file string) error {
 		}
 	}
 
+	if err != nil {
+		return err
+	}
+
 	// post-process entries array
 
 	for _, entry := range entries {
@@ -208,77 +228,37 @@ func serializeEntries(file string, debug bool) error {
 	var err error
 	var fp  *os.File
 
+	if debug {
+		return hsformat.WriteJSON(file, entries)
+	}
+
 	if fp, err = os.Create(file); err != nil {
 		return err
 	}
 
 	defer fp.Close()
 
-	bw := bufio.NewWriter(fp)
-
-	if debug {
-		var bs []byte
-		bs, err = json.MarshalIndent(entries, "", "\t")
-
-		bw.Write(bs)
-		bw.Flush()
-
-		return err
-	}
-
-	// package type: CPE dictionary
-	binary.Write(bw, binary.LittleEndian, uint16(1))
-	// package version
-	binary.Write(bw, binary.LittleEndian, uint16(1))
-	// number of entries
-	binary.Write(bw, binary.LittleEndian, uint32(len(entries)))
+	lst := make([]*hsformat.CPEEntry, 0, len(entries))
 
 	for _, entry := range entries {
 		// CPE: [cpe:/]o:linux:linux_kernel
-		binary.Write(bw, binary.LittleEndian, uint16(len(entry.CPE) - 5))
-		bw.WriteString(entry.CPE[5:])
-
-		// number of tokens
-		binary.Write(bw, binary.LittleEndian, uint8(len(entry.Tokens)))
-
-		for _, token := range entry.Tokens {
-			token = regexp.QuoteMeta(token)
-
-			// token: Linux, Kernel
-			binary.Write(bw, binary.LittleEndian, uint16(len(token)))
-			bw.WriteString(token)
+		ent := &hsformat.CPEEntry {
+			CPE:    entry.CPE[5:],
+			Tokens: entry.Tokens,
 		}
 
-		// number of versions
-		binary.Write(bw, binary.LittleEndian, uint32(len(entry.Versions)))
-
 		for _, subentry := range entry.Versions {
-			// CPE: 3.10.0::~~~~arm64~
-			binary.Write(bw, binary.LittleEndian, uint16(len(subentry.CPE)))
-			bw.WriteString(subentry.CPE)
-
-			// version: 3.10.0
-			binary.Write(bw, binary.LittleEndian, uint16(len(subentry.Version)))
-			bw.WriteString(subentry.Version)
-
-			// number of tokens
-			binary.Write(bw, binary.LittleEndian, uint8(len(subentry.Tokens)))
-
-			for _, token := range subentry.Tokens {
-				token = regexp.QuoteMeta(token)
-
-				// token: on, ARM64, architecture
-				binary.Write(bw, binary.LittleEndian, uint16(len(token)))
-				bw.WriteString(token)
-			}
+			ent.Versions = append(ent.Versions, &hsformat.CPEVersion {
+				CPE:     subentry.CPE,
+				Version: subentry.Version,
+				Tokens:  subentry.Tokens,
+			})
 		}
-	}
-
-	binary.Write(bw, binary.LittleEndian, uint32(0))
 
-	bw.Flush()
+		lst = append(lst, ent)
+	}
 
-	return err
+	return hsformat.WriteCPEDictionary(fp, lst)
 }
 
 // Entry point of the application.
@@ -299,14 +279,14 @@ func main() {
 	println("Parsing CPE dictionary...")
 
 	if err = parseInput(os.Args[1]); err != nil {
-		println(err)
+		println(err.Error())
 		os.Exit(-1)
 	}
 
 	println("Writing parsed data...")
 
 	if err = serializeEntries(os.Args[2], dbg); err != nil {
-		println(err)
+		println(err.Error())
 		os.Exit(-1)
 	}
 }
\ No newline at end of file
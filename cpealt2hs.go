@@ -5,8 +5,8 @@ import (
 	"bufio"
 	"strings"
 	"net/url"
-	"encoding/json"
-	"encoding/binary"
+
+	"github.com/RoliSoft/Host-Scanner-Scripts/hsformat"
 )
 
 var entries [][]string
@@ -49,47 +49,28 @@ func serializeEntries(file string, debug bool) error {
 	var err error
 	var fp  *os.File
 
+	if debug {
+		return hsformat.WriteJSON(file, entries)
+	}
+
 	if fp, err = os.Create(file); err != nil {
 		return err
 	}
 
 	defer fp.Close()
 
-	bw := bufio.NewWriter(fp)
-
-	if debug {
-		var bs []byte
-		bs, err = json.MarshalIndent(entries, "", "\t")
-
-		bw.Write(bs)
-		bw.Flush()
+	aliases := make([][]string, len(entries))
 
-		return err
-	}
-
-	// package type: CPE aliases
-	binary.Write(bw, binary.LittleEndian, uint16(2))
-	// package version
-	binary.Write(bw, binary.LittleEndian, uint16(1))
-	// number of entries
-	binary.Write(bw, binary.LittleEndian, uint32(len(entries)))
-
-	for _, entry := range entries {
-		// number of aliases in entry
-		binary.Write(bw, binary.LittleEndian, uint16(len(entry)))
+	for i, entry := range entries {
+		aliases[i] = make([]string, len(entry))
 
-		for _, alias := range entry {
+		for j, alias := range entry {
 			alias, _ = url.QueryUnescape(alias)
-			binary.Write(bw, binary.LittleEndian, uint16(len(alias) - 5))
-			bw.WriteString(alias[5:])
+			aliases[i][j] = alias[5:]
 		}
 	}
 
-	binary.Write(bw, binary.LittleEndian, uint32(0))
-
-	bw.Flush()
-
-	return err
+	return hsformat.WriteCPEAliases(fp, aliases)
 }
 
 // Entry point of the application.
@@ -110,14 +91,14 @@ func main() {
 	println("Parsing CPE aliases list...")
 
 	if err = parseInput(os.Args[1]); err != nil {
-		println(err)
+		println(err.Error())
 		os.Exit(-1)
 	}
 
 	println("Writing parsed data...")
 
 	if err = serializeEntries(os.Args[2], dbg); err != nil {
-		println(err)
+		println(err.Error())
 		os.Exit(-1)
 	}
 }
\ No newline at end of file
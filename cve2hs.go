@@ -1,177 +1,2460 @@
 package main
 
 import (
+	"io"
 	"os"
 	"fmt"
 	"time"
-	"bufio"
+	"sort"
+	"sync"
+	"errors"
+	"runtime"
 	"strings"
 	"net/url"
-	"io/ioutil"
+	"path/filepath"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/xml"
 	"encoding/json"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/RoliSoft/Host-Scanner-Scripts/cvedb"
+	"github.com/RoliSoft/Host-Scanner-Scripts/cvefeed"
+	"github.com/RoliSoft/Host-Scanner-Scripts/hsformat"
 )
 
 var entries entry
+var output *database
+var index map[string]seenItem
+var weaknesses []cweEntry
+var timestamp time.Time
+var stamp sync.Mutex
+var queue chan item
+var sources []*source
+var aliases [][]string
+var dictionary cvefeed.Dictionary
+
+// version of cve2hs, stored in the `meta` table of the databases it builds or
+// updates. It is increased whenever the contents written change.
+const version = "1.0"
+
+// maxConfigs is the number of configuration node IDs reserved for each vulnerability.
+const maxConfigs = 10000
 
 type entry struct {
-	Items []struct {
-		Name 	string `xml:"cve-id"`
-		Date 	string `xml:"published-datetime"`
-		Summary string `xml:"summary"`
-		Weakness struct {
-			Name string `xml:"id,attr"`
-		} `xml:"cwe"`
-		Classification struct {
-			Severity 				float64 `xml:"score"`
-			AccessVector 			string `xml:"access-vector"`
-			AccessComplexity 		string `xml:"access-complexity"`
-			Authentication 			string `xml:"authentication"`
-			ConfidentialityImpact 	string `xml:"confidentiality-impact"`
-			IntegrityImpact 		string `xml:"integrity-impact"`
-			AvailablityImpact 		string `xml:"availability-impact"`
-		} `xml:"cvss>base_metrics"`
-		Software []string `xml:"vulnerable-software-list>product"`
-	} `xml:"entry"`
-}
-
-// Reads the specified XML file and sends the entries for processing.
-func parseInput(file string) error {
-	var err error
-	var fp *os.File
+	Published string `xml:"pub_date,attr"`
+	Items []item `xml:"entry"`
+}
+
+type item struct {
+	Name 	string `xml:"cve-id"`
+	Date 	string `xml:"published-datetime"`
+	Modified string `xml:"last-modified-datetime"`
+	Summary string `xml:"summary"`
+	Weaknesses []weakness `xml:"cwe"`
+	Classification struct {
+		Severity 				float64 `xml:"score"`
+		AccessVector 			string `xml:"access-vector"`
+		AccessComplexity 		string `xml:"access-complexity"`
+		Authentication 			string `xml:"authentication"`
+		ConfidentialityImpact 	string `xml:"confidentiality-impact"`
+		IntegrityImpact 		string `xml:"integrity-impact"`
+		AvailablityImpact 		string `xml:"availability-impact"`
+		Source 					string `xml:"source"`
+		Generated 				string `xml:"generated-on-datetime"`
+	} `xml:"cvss>base_metrics"`
+	SeverityV3 float64 `xml:"-"`
+	SeverityV4 float64 `xml:"-"`
+	Software []string `xml:"vulnerable-software-list>product"`
+	Products []product `xml:"-"`
+	Ranges []versionRange `xml:"-"`
+	Tests []logicalTest `xml:"vulnerable-configuration>logical-test" json:"-"`
+	XMLRefs []xmlReference `xml:"references" json:"-"`
+	References []reference `xml:"-"`
+	Configs []*config `xml:"-"`
+	Unenriched bool `xml:"-"`
+	Status string `xml:"-"`
+	Inferred []cvefeed.Range `xml:"-" json:",omitempty"`
+}
+
+// Version range of a CPE name, see the `versionStartIncluding` and similar
+// fields of the NVD configuration nodes.
+type versionRange struct {
+	CPE string
+	StartIncluding, StartExcluding, EndIncluding, EndExcluding string
+}
+
+// Node of a configuration tree, such as "Firefox running on Windows",
+// expressed as an AND node of two OR nodes with a CPE match each.
+type config struct {
+	Operator string
+	Negate bool
+	Children []*config
+	Matches []configMatch
+}
+
+type configMatch struct {
+	versionRange
+	Vulnerable bool
+}
+
+// Configuration node of the XML 2.0 schema, see `cpe-lang:logical-test`.
+type logicalTest struct {
+	Operator string `xml:"operator,attr"`
+	Negate bool `xml:"negate,attr"`
+	Tests []logicalTest `xml:"logical-test"`
+	Facts []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"fact-ref"`
+}
+
+// Reference to an advisory, patch or other resource, with tags such as
+// `Patch`, `Vendor Advisory`, `Exploit` or `Third Party Advisory`.
+type reference struct {
+	URL, Source string
+	Tags []string
+}
+
+// Reference of the XML 2.0 schema, see `vuln:references`.
+type xmlReference struct {
+	Type   string `xml:"reference_type,attr"`
+	Source string `xml:"source"`
+	Link   struct {
+		URL string `xml:"href,attr"`
+	} `xml:"reference"`
+}
+
+// Weakness or category of the MITRE CWE catalog.
+type cweEntry struct {
+	ID          string `xml:"ID,attr"`
+	Name        string `xml:"Name,attr"`
+	Description string `xml:"Description"`
+	Summary     string `xml:"Summary"`
+	Related     []struct {
+		Nature  string `xml:"Nature,attr"`
+		ID      string `xml:"CWE_ID,attr"`
+		View    string `xml:"View_ID,attr"`
+		Ordinal string `xml:"Ordinal,attr"`
+	} `xml:"Related_Weaknesses>Related_Weakness"`
+}
+
+// Input file or directory, recorded in the `sources` table along with the
+// latest feed timestamp found within and the SHA-256 hash of the file.
+type source struct {
+	Name string
+	Timestamp time.Time
+	Hash string
+}
+
+// File to be parsed, along with the input it was found in.
+type feedFile struct {
+	Path string
+	Source *source
+}
+
+// Holds the properties of a previously added entry, in order to decide
+// whether an entry with the same CVE ID should replace it.
+type seenItem struct {
+	Index int
+	Modified time.Time
+	Unenriched bool
+}
+
+// Holds the database being written, along with the statements used to insert
+// the entries as soon as they are parsed.
+type database struct {
+	db *sql.DB
+	tx *sql.Tx
+	update bool
+	fts bool
+	rejected bool
+	stm1, stm2, stm3, stm4, stm5, stm6, stm7, stm8, stm9, stm10, stm11 *sql.Stmt
+}
+
+// Affected product of a CNA record, see CVE JSON 5 `containers.cna.affected`.
+type product struct {
+	Vendor, Product, Version, LessThan, LessThanOrEqual, Status string
+}
+
+type weakness struct {
+	Name string `xml:"id,attr"`
+}
+
+// Entry of the `CVE_Items` array of an NVD JSON 1.1 feed, as published in the
+// nvdcve-1.1-YYYY.json files.
+type nvdItem struct {
+	CVE struct {
+		Meta struct {
+			ID string `json:"ID"`
+		} `json:"CVE_data_meta"`
+		ProblemType struct {
+			Data []struct {
+				Description []struct {
+					Value string `json:"value"`
+				} `json:"description"`
+			} `json:"problemtype_data"`
+		} `json:"problemtype"`
+		Description struct {
+			Data []struct {
+				Lang  string `json:"lang"`
+				Value string `json:"value"`
+			} `json:"description_data"`
+		} `json:"description"`
+		References struct {
+			Data []struct {
+				URL       string   `json:"url"`
+				RefSource string   `json:"refsource"`
+				Tags      []string `json:"tags"`
+			} `json:"reference_data"`
+		} `json:"references"`
+	} `json:"cve"`
+	Configurations struct {
+		Nodes []nvdNode `json:"nodes"`
+	} `json:"configurations"`
+	Impact struct {
+		V3 struct {
+			CVSS cvssData `json:"cvssV3"`
+		} `json:"baseMetricV3"`
+		V2 struct {
+			CVSS cvssData `json:"cvssV2"`
+		} `json:"baseMetricV2"`
+	} `json:"impact"`
+	PublishedDate    string `json:"publishedDate"`
+	LastModifiedDate string `json:"lastModifiedDate"`
+}
+
+// Entry of the `vulnerabilities` array of an NVD CVE API 2.0 response page, as
+// saved from the /rest/json/cves/2.0 endpoint, or of an NVD JSON 2.0 feed, as
+// published in the nvdcve-2.0-YYYY.json files, which share the same shape.
+type nvdVuln struct {
+	CVE struct {
+		ID           string `json:"id"`
+		Status       string `json:"vulnStatus"`
+		Tags         []struct {
+			Tags []string `json:"tags"`
+		} `json:"cveTags"`
+		Published    string `json:"published"`
+		LastModified string `json:"lastModified"`
+		Descriptions []nvdLangString `json:"descriptions"`
+		Metrics struct {
+			V40 []nvdMetric `json:"cvssMetricV40"`
+			V31 []nvdMetric `json:"cvssMetricV31"`
+			V30 []nvdMetric `json:"cvssMetricV30"`
+			V2  []nvdMetric `json:"cvssMetricV2"`
+		} `json:"metrics"`
+		Weaknesses []struct {
+			Description []nvdLangString `json:"description"`
+		} `json:"weaknesses"`
+		References []struct {
+			URL    string   `json:"url"`
+			Source string   `json:"source"`
+			Tags   []string `json:"tags"`
+		} `json:"references"`
+		Configurations []struct {
+			Operator string `json:"operator"`
+			Negate   bool   `json:"negate"`
+			Nodes    []nvdNode `json:"nodes"`
+		} `json:"configurations"`
+	} `json:"cve"`
+}
+
+// CVE JSON 5 record, as found in the cves/ directory of the cvelistV5 repository.
+type cveRecord struct {
+	Metadata struct {
+		ID          string `json:"cveId"`
+		Assigner    string `json:"assignerShortName"`
+		State       string `json:"state"`
+		Published   string `json:"datePublished"`
+		Updated     string `json:"dateUpdated"`
+	} `json:"cveMetadata"`
+	Containers struct {
+		CNA struct {
+			Affected []struct {
+				Vendor   string `json:"vendor"`
+				Product  string `json:"product"`
+				CPEs     []string `json:"cpes"`
+				Versions []struct {
+					Version         string `json:"version"`
+					Status          string `json:"status"`
+					LessThan        string `json:"lessThan"`
+					LessThanOrEqual string `json:"lessThanOrEqual"`
+				} `json:"versions"`
+				DefaultStatus string `json:"defaultStatus"`
+			} `json:"affected"`
+			Descriptions []nvdLangString `json:"descriptions"`
+			Tags []string `json:"tags"`
+			Metrics []struct {
+				V40 *cvssData `json:"cvssV4_0"`
+				V31 *cvssData `json:"cvssV3_1"`
+				V30 *cvssData `json:"cvssV3_0"`
+				V2  *cvssData `json:"cvssV2_0"`
+			} `json:"metrics"`
+			ProblemTypes []struct {
+				Descriptions []struct {
+					CWE string `json:"cweId"`
+				} `json:"descriptions"`
+			} `json:"problemTypes"`
+			References []struct {
+				URL  string   `json:"url"`
+				Name string   `json:"name"`
+				Tags []string `json:"tags"`
+			} `json:"references"`
+		} `json:"cna"`
+	} `json:"containers"`
+}
+
+type nvdLangString struct {
+	Lang  string `json:"lang"`
+	Value string `json:"value"`
+}
+
+type nvdMetric struct {
+	Source string   `json:"source"`
+	Type   string   `json:"type"`
+	Data   cvssData `json:"cvssData"`
+}
+
+type cvssData struct {
+	BaseScore             float64 `json:"baseScore"`
+	AttackVector          string  `json:"attackVector"`
+	AccessVector          string  `json:"accessVector"`
+	AccessComplexity      string  `json:"accessComplexity"`
+	Authentication        string  `json:"authentication"`
+	ConfidentialityImpact string  `json:"confidentialityImpact"`
+	IntegrityImpact       string  `json:"integrityImpact"`
+	AvailabilityImpact    string  `json:"availabilityImpact"`
+}
+
+// Configuration node of both the JSON 1.1 feeds and the API 2.0, which
+// differ only in the naming of the CPE match fields.
+type nvdNode struct {
+	Operator string `json:"operator"`
+	Negate   bool   `json:"negate"`
+	Children []nvdNode  `json:"children"`
+	Matches  []nvdMatch `json:"cpe_match"`
+	CPEMatch []nvdMatch `json:"cpeMatch"`
+}
+
+type nvdMatch struct {
+	Vulnerable     bool   `json:"vulnerable"`
+	URI            string `json:"cpe23Uri"`
+	Criteria       string `json:"criteria"`
+	StartIncluding string `json:"versionStartIncluding"`
+	StartExcluding string `json:"versionStartExcluding"`
+	EndIncluding   string `json:"versionEndIncluding"`
+	EndExcluding   string `json:"versionEndExcluding"`
+}
+
+// Reads the specified XML or JSON feeds and sends the entries for processing.
+// Directories are scanned for XML and JSON feeds, saved API 2.0 pages or
+// CVE JSON 5 records, such as a checkout of the cvelistV5 repository. The files
+// are parsed concurrently, while the entries are stored by the calling goroutine,
+// so the database is only written to from a single one.
+func parseInput(files []string) error {
+	var err  error
+	var perr error
+	var lock sync.Mutex
+	var wg   sync.WaitGroup
+
+	fail := func(err error) {
+		lock.Lock()
+		if perr == nil {
+			perr = err
+		}
+		lock.Unlock()
+	}
+
+	index = make(map[string]seenItem)
+	queue = make(chan item, 64)
+	paths := make(chan feedFile)
+
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for file := range paths {
+				if err := parseFile(file.Path, file.Source); err != nil {
+					fail(fmt.Errorf("%s: %s", file.Path, err.Error()))
+				}
+			}
+		}()
+	}
+
+	for _, file := range files {
+		sources = append(sources, &source { Name: filepath.Base(file) })
+	}
+
+	go func() {
+		for i, file := range files {
+			if err := walkInput(file, sources[i], paths); err != nil {
+				fail(err)
+				break
+			}
+		}
+
+		close(paths)
+		wg.Wait()
+		close(queue)
+	}()
+
+	// keep draining the queue after an error, so the parsers can finish
+
+	for ent := range queue {
+		if err == nil {
+			err = storeItem(ent)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return perr
+}
+
+// Sends the specified file, or the XML and JSON files within the specified
+// directory and its subdirectories, to the parsers. Files specified directly
+// are also hashed, while the parsers are already reading them.
+func walkInput(file string, src *source, paths chan<- feedFile) error {
+	return cvefeed.Walk(file, func(path string) error {
+		var err error
+
+		paths <- feedFile { path, src }
+
+		if path == file {
+			src.Hash, err = hashFile(file)
+		}
+
+		return err
+	})
+}
+
+// Calculates the SHA-256 hash of the specified file.
+func hashFile(file string) (string, error) {
+	var err error
+	var fp  *os.File
+
+	if fp, err = os.Open(file); err != nil {
+		return "", err
+	}
+
+	defer fp.Close()
+
+	hash := sha256.New()
+
+	if _, err = io.Copy(hash, fp); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Reads the specified file based on its extension.
+func parseFile(file string, src *source) error {
+	if cvefeed.IsJSON(file) {
+		return parseJSONFeed(file, src)
+	}
+
+	return parseXMLFeed(file, src)
+}
+
+// Keeps track of the latest feed timestamp overall and within the specified
+// input, in order to be recorded in the database.
+func updateTimestamp(src *source, date string) {
+	t := parseDate(date)
+
+	stamp.Lock()
+	defer stamp.Unlock()
+
+	if t.After(timestamp) {
+		timestamp = t
+	}
+
+	if t.After(src.Timestamp) {
+		src.Timestamp = t
+	}
+}
+
+// Sends the specified entry from one of the parsers to be stored.
+func addItem(ent item) error {
+	if dictionary != nil {
+		ent.Inferred = ent.inferRanges()
+	}
+
+	queue <- ent
+	return nil
+}
+
+// Writes the specified entry to the database, or adds it to the global `entries`
+// list when no database is open. The previously added entry with the same CVE ID,
+// or the one stored in the database being updated, is replaced if it was not
+// modified later. Entries enriched by NVD always take precedence over CNA records.
+func storeItem(ent item) error {
+	if len(ent.Status) == 0 {
+		ent.Status = summaryStatus(ent.Summary)
+	}
+
+	seen := seenItem {
+		Index:      len(entries.Items),
+		Modified:   parseDate(ent.Modified),
+		Unenriched: ent.Unenriched,
+	}
+
+	prev, ok := index[ent.Name]
+
+	if !ok && output != nil && output.update {
+		prev, ok = output.stored(ent.Name)
+	}
+
+	if ok {
+		if prev.Unenriched != ent.Unenriched {
+			if !prev.Unenriched {
+				return nil
+			}
+		} else if seen.Modified.Before(prev.Modified) {
+			return nil
+		}
+
+		seen.Index = prev.Index
+	}
+
+	index[ent.Name] = seen
+
+	if output != nil {
+		return output.write(&ent, ok)
+	}
+
+	if ok {
+		entries.Items[seen.Index] = ent
+	} else {
+		entries.Items = append(entries.Items, ent)
+	}
+
+	return nil
+}
+
+// Reads the specified NVD XML 2.0 feed, optionally gzip compressed, such as
+// a yearly feed or a merged file. The entries are decoded one at a time, so
+// the whole file is never held in memory. The namespaces of the feed, such as
+// `vuln:` and `cvss:`, need not be stripped, since only the local names of the
+// elements are matched.
+func parseXMLFeed(file string, src *source) error {
+	var err error
+	var fp  io.ReadCloser
+	var tok xml.Token
+
+	if fp, err = hsformat.Open(file); err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	dec := xml.NewDecoder(fp)
+
+	for {
+		if tok, err = dec.Token(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+
+			break
+		}
+
+		elem, ok := tok.(xml.StartElement)
+
+		if !ok {
+			continue
+		}
+
+		switch elem.Name.Local {
+		case "nvd":
+			for _, attr := range elem.Attr {
+				if attr.Name.Local == "pub_date" {
+					updateTimestamp(src, attr.Value)
+				}
+			}
+
+		case "entry":
+			var ent item
+
+			if err = dec.DecodeElement(&ent, &elem); err != nil {
+				return err
+			}
+
+			if err = addXMLItem(ent); err != nil {
+				return err
+			}
+		}
+	}
+
+	return err
+}
+
+// Returns the status of an entry based on the prefix NVD adds to the summary
+// of rejected, disputed and reserved entries, such as "** DISPUTED **".
+func summaryStatus(summary string) string {
+	switch {
+	case strings.HasPrefix(summary, "** REJECT **"):
+		return "rejected"
+	case strings.HasPrefix(summary, "** DISPUTED **"):
+		return "disputed"
+	case strings.HasPrefix(summary, "** RESERVED **"):
+		return "reserved"
+	}
+
+	return "active"
+}
+
+// Checks whether the specified tag is found within the list, such as "disputed".
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Maps the logical tests and references of an entry of the XML 2.0 schema.
+func addXMLItem(ent item) error {
+	for i := range ent.Tests {
+		ent.Configs = append(ent.Configs, ent.convertTest(&ent.Tests[i]))
+	}
+
+	for _, ref := range ent.XMLRefs {
+		ent.References = append(ent.References, reference {
+			URL:    ref.Link.URL,
+			Source: ref.Source,
+			Tags:   classifyReference(ref.Type, ref.Source, ref.Link.URL),
+		})
+	}
+
+	ent.Tests = nil
+	ent.XMLRefs = nil
+
+	return addItem(ent)
+}
+
+// Classifies a reference of the XML 2.0 schema, which only distinguishes
+// patches and vendor advisories, based on its source and URL.
+func classifyReference(typ, source, link string) []string {
+	var tags []string
+
+	switch typ {
+	case "PATCH":
+		tags = append(tags, "Patch")
+	case "VENDOR_ADVISORY":
+		tags = append(tags, "Vendor Advisory")
+	}
+
+	link = strings.ToLower(link)
+
+	switch {
+	case source == "EXPLOIT-DB" || strings.Contains(link, "exploit-db.com/") || strings.Contains(link, "packetstormsecurity.") || strings.Contains(link, "/exploits/"):
+		tags = append(tags, "Exploit")
+	case len(tags) == 0 && thirdPartySources[source]:
+		tags = append(tags, "Third Party Advisory")
+	}
+
+	return tags
+}
+
+// Sources of the XML 2.0 schema which publish advisories independently of the vendor.
+var thirdPartySources = map[string]bool {
+	"BID": true, "CERT": true, "CERT-VN": true, "SECUNIA": true, "SECTRACK": true,
+	"OSVDB": true, "XF": true, "VUPEN": true, "BUGTRAQ": true, "FULLDISC": true,
+}
+
+// Converts a configuration node of the XML 2.0 schema. Since the fact
+// references have no vulnerable flag, the CPE names found in the vulnerable
+// software list are flagged as such.
+func (ent *item) convertTest(test *logicalTest) *config {
+	conf := &config {
+		Operator: test.Operator,
+		Negate:   test.Negate,
+	}
+
+	for i := range test.Tests {
+		conf.Children = append(conf.Children, ent.convertTest(&test.Tests[i]))
+	}
+
+	for _, fact := range test.Facts {
+		match := configMatch { }
+		match.CPE = fact.Name
+
+		for _, cpe := range ent.Software {
+			if cpe == fact.Name {
+				match.Vulnerable = true
+				break
+			}
+		}
+
+		conf.Matches = append(conf.Matches, match)
+	}
+
+	return conf
+}
+
+// Reads the specified MITRE CWE catalog, such as cwec_v4.14.xml.
+func parseCatalog(file string) error {
+	var err error
+	var fp  io.ReadCloser
+
+	if fp, err = hsformat.Open(file); err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	var lst struct {
+		Weaknesses []cweEntry `xml:"Weaknesses>Weakness"`
+		Categories []cweEntry `xml:"Categories>Category"`
+	}
+
+	if err = xml.NewDecoder(fp).Decode(&lst); err != nil {
+		return err
+	}
+
+	weaknesses = append(lst.Weaknesses, lst.Categories...)
+
+	return err
+}
+
+// Reads the specified CPE aliases package generated by cpealt2hs, such as
+// cpe-aliases.dat.gz.
+func parseAliases(file string) error {
+	var err error
+	var fp  io.ReadCloser
+
+	if fp, err = hsformat.Open(file); err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	aliases, err = hsformat.ReadCPEAliases(fp)
+
+	return err
+}
+
+// Reads the specified CPE dictionary package generated by cpe2hs, such as
+// cpe-list.dat.gz, and indexes its products by the tokens of their names.
+func parseDictionary(file string) error {
+	var err error
+	var fp  io.ReadCloser
+	var lst []*hsformat.CPEEntry
+
+	if fp, err = hsformat.Open(file); err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	if lst, err = hsformat.ReadCPEDictionary(fp); err != nil {
+		return err
+	}
+
+	dictionary = make(cvefeed.Dictionary)
+
+	for _, ent := range lst {
+		dictionary.Add(ent.CPE)
+	}
+
+	return err
+}
+
+// Extracts the version ranges specified in the summary of the entry, see
+// cvefeed.InferRanges, preferring the products listed in the entry.
+func (ent *item) inferRanges() []cvefeed.Range {
+	listed := make(map[string]bool)
+
+	for _, cpe := range ent.Software {
+		listed[strings.ToLower(productName(cpe))] = true
+	}
+
+	for _, rng := range ent.Ranges {
+		listed[strings.ToLower(productName(rng.CPE))] = true
+	}
+
+	return dictionary.InferRanges(ent.Summary, listed)
+}
+
+// Returns the `part:vendor:product` name of the specified CPE name.
+func productName(cpe string) string {
+	elems := splitCPE(cpe)
+
+	for len(elems) < 3 {
+		elems = append(elems, "")
+	}
+
+	return strings.Join(elems[:3], ":")
+}
+
+// Returns the parent of the weakness, preferring the primary one within the
+// Research Concepts view, or nil if it has none.
+func (cwe *cweEntry) parent() interface{} {
+	var parent interface{}
+
+	for _, rel := range cwe.Related {
+		if rel.Nature != "ChildOf" {
+			continue
+		}
+
+		if rel.View == "1000" && rel.Ordinal == "Primary" {
+			return "CWE-" + rel.ID
+		}
+
+		if parent == nil {
+			parent = "CWE-" + rel.ID
+		}
+	}
+
+	return parent
+}
+
+// Reads the specified NVD JSON 1.1 or 2.0 feed, API 2.0 response page or CVE JSON 5
+// record, optionally gzip compressed, and maps its entries to the structure
+// used by the XML 2.0 schema. Documents of any other shape are skipped. The
+// entries of the feeds and pages are decoded one at a time, as they are
+// mapped, so the whole file is never held in memory.
+func parseJSONFeed(file string, src *source) error {
+	var err error
+	var fp  io.ReadCloser
+
+	if fp, err = hsformat.Open(file); err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	var rec cveRecord
+
+	decode := func(v interface{}) func(dec *json.Decoder) error {
+		return func(dec *json.Decoder) error {
+			return dec.Decode(v)
+		}
+	}
+
+	updated := func(dec *json.Decoder) error {
+		var date string
+
+		if err := dec.Decode(&date); err != nil {
+			return err
+		}
+
+		updateTimestamp(src, date)
+		return nil
+	}
+
+	err = cvefeed.Decode(fp, map[string]func(dec *json.Decoder) error {
+		"CVE_data_timestamp": updated,
+		"timestamp":          updated,
+		"CVE_Items": func(dec *json.Decoder) error {
+			return cvefeed.Each(dec, func() error {
+				var cve nvdItem
+
+				if err := dec.Decode(&cve); err != nil {
+					return err
+				}
+
+				return parseFeedItem(&cve)
+			})
+		},
+		"vulnerabilities": func(dec *json.Decoder) error {
+			return cvefeed.Each(dec, func() error {
+				var vuln nvdVuln
+
+				if err := dec.Decode(&vuln); err != nil {
+					return err
+				}
+
+				return parsePageItem(&vuln)
+			})
+		},
+		"cveMetadata": decode(&rec.Metadata),
+		"containers":  decode(&rec.Containers),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	updateTimestamp(src, rec.Metadata.Updated)
+
+	return parseRecordItem(&rec)
+}
+
+// Maps an entry of a JSON 1.1 feed.
+func parseFeedItem(cve *nvdItem) error {
+	ent := item {
+		Name:     cve.CVE.Meta.ID,
+		Date:     cve.PublishedDate,
+		Modified: cve.LastModifiedDate,
+	}
+
+	for _, descr := range cve.CVE.Description.Data {
+		if descr.Lang == "en" {
+			ent.Summary = descr.Value
+			break
+		}
+	}
+
+	for _, pt := range cve.CVE.ProblemType.Data {
+		for _, descr := range pt.Description {
+			ent.Weaknesses = append(ent.Weaknesses, weakness { descr.Value })
+		}
+	}
+
+	// prefer CVSS v2 for consistency with the XML feeds, fall back to v3 otherwise
+
+	if v2 := &cve.Impact.V2.CVSS; len(v2.AccessVector) != 0 {
+		ent.setBaseMetrics(v2, "nvd@nist.gov", cve.LastModifiedDate)
+	} else if v3 := cve.Impact.V3.CVSS; len(v3.AttackVector) != 0 {
+		ent.Classification.Severity     = v3.BaseScore
+		ent.Classification.AccessVector = v3.AttackVector
+	}
+
+	ent.SeverityV3 = cve.Impact.V3.CVSS.BaseScore
+
+	for _, ref := range cve.CVE.References.Data {
+		ent.References = append(ent.References, reference { ref.URL, ref.RefSource, ref.Tags })
+	}
+
+	ent.collectMatches(cve.Configurations.Nodes)
+
+	for i := range cve.Configurations.Nodes {
+		ent.Configs = append(ent.Configs, convertNode(&cve.Configurations.Nodes[i]))
+	}
+
+	return addItem(ent)
+}
+
+// Maps an entry of an API 2.0 response page or JSON 2.0 feed.
+func parsePageItem(vuln *nvdVuln) error {
+	cve := &vuln.CVE
+	ent := item {
+		Name:     cve.ID,
+		Date:     cve.Published,
+		Modified: cve.LastModified,
+	}
+
+	if cve.Status == "Rejected" {
+		ent.Status = "rejected"
+	}
+
+	for _, tags := range cve.Tags {
+		if hasTag(tags.Tags, "disputed") && len(ent.Status) == 0 {
+			ent.Status = "disputed"
+		}
+	}
+
+	for _, descr := range cve.Descriptions {
+		if descr.Lang == "en" {
+			ent.Summary = descr.Value
+			break
+		}
+	}
+
+	for _, weak := range cve.Weaknesses {
+		for _, descr := range weak.Description {
+			found := false
+
+			for _, prev := range ent.Weaknesses {
+				if prev.Name == descr.Value {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				ent.Weaknesses = append(ent.Weaknesses, weakness { descr.Value })
+			}
+		}
+	}
+
+	v2 := primaryMetric(cve.Metrics.V2)
+	v3 := primaryMetric(cve.Metrics.V31)
+	v4 := primaryMetric(cve.Metrics.V40)
+
+	if v3 == nil {
+		v3 = primaryMetric(cve.Metrics.V30)
+	}
+
+	if v3 != nil {
+		ent.SeverityV3 = v3.Data.BaseScore
+	}
+
+	if v4 != nil {
+		ent.SeverityV4 = v4.Data.BaseScore
+	}
+
+	// prefer CVSS v2 for consistency with the XML feeds, then the newest version available
+
+	if v2 != nil {
+		ent.setBaseMetrics(&v2.Data, v2.Source, cve.LastModified)
+	} else if v3 != nil {
+		ent.Classification.Severity     = v3.Data.BaseScore
+		ent.Classification.AccessVector = v3.Data.AttackVector
+	} else if v4 != nil {
+		ent.Classification.Severity     = v4.Data.BaseScore
+		ent.Classification.AccessVector = v4.Data.AttackVector
+	}
+
+	for _, ref := range cve.References {
+		ent.References = append(ent.References, reference { ref.URL, ref.Source, ref.Tags })
+	}
+
+	for _, conf := range cve.Configurations {
+		ent.collectMatches(conf.Nodes)
+
+		if len(conf.Nodes) == 1 && len(conf.Operator) == 0 {
+			ent.Configs = append(ent.Configs, convertNode(&conf.Nodes[0]))
+			continue
+		}
+
+		root := &config {
+			Operator: conf.Operator,
+			Negate:   conf.Negate,
+		}
+
+		for i := range conf.Nodes {
+			root.Children = append(root.Children, convertNode(&conf.Nodes[i]))
+		}
+
+		ent.Configs = append(ent.Configs, root)
+	}
+
+	return addItem(ent)
+}
+
+// Maps a CVE JSON 5 record published by a CNA. Since these are not yet
+// enriched by NVD, the affected products are stored by vendor and product
+// name, along with any CPE names provided by the CNA.
+func parseRecordItem(rec *cveRecord) error {
+	if len(rec.Metadata.ID) == 0 {
+		return nil
+	}
+
+	if rec.Metadata.State == "REJECTED" {
+		return addItem(item {
+			Name:       rec.Metadata.ID,
+			Date:       rec.Metadata.Published,
+			Modified:   rec.Metadata.Updated,
+			Unenriched: true,
+			Status:     "rejected",
+		})
+	}
+
+	if rec.Metadata.State != "PUBLISHED" {
+		return nil
+	}
+
+	cna := &rec.Containers.CNA
+	ent := item {
+		Name:       rec.Metadata.ID,
+		Date:       rec.Metadata.Published,
+		Modified:   rec.Metadata.Updated,
+		Unenriched: true,
+	}
+
+	if hasTag(cna.Tags, "disputed") {
+		ent.Status = "disputed"
+	}
+
+	for _, descr := range cna.Descriptions {
+		if strings.HasPrefix(descr.Lang, "en") {
+			ent.Summary = descr.Value
+			break
+		}
+	}
+
+	for _, pt := range cna.ProblemTypes {
+		for _, descr := range pt.Descriptions {
+			if len(descr.CWE) != 0 {
+				ent.Weaknesses = append(ent.Weaknesses, weakness { descr.CWE })
+			}
+		}
+	}
+
+	var v2, v3, v4 *cvssData
+
+	for _, metric := range cna.Metrics {
+		if v2 == nil {
+			v2 = metric.V2
+		}
+
+		if v3 == nil {
+			if v3 = metric.V31; v3 == nil {
+				v3 = metric.V30
+			}
+		}
+
+		if v4 == nil {
+			v4 = metric.V40
+		}
+	}
+
+	if v3 != nil {
+		ent.SeverityV3 = v3.BaseScore
+	}
+
+	if v4 != nil {
+		ent.SeverityV4 = v4.BaseScore
+	}
+
+	if v2 != nil {
+		ent.setBaseMetrics(v2, rec.Metadata.Assigner, rec.Metadata.Updated)
+	} else if v3 != nil {
+		ent.Classification.Severity     = v3.BaseScore
+		ent.Classification.AccessVector = v3.AttackVector
+	} else if v4 != nil {
+		ent.Classification.Severity     = v4.BaseScore
+		ent.Classification.AccessVector = v4.AttackVector
+	}
+
+	for _, ref := range cna.References {
+		var tags []string
+
+		// CNA tags are in the form of `vendor-advisory`, convert them to `Vendor Advisory`
+		// and skip the `x_` prefixed ones, which are not part of the schema
+		for _, tag := range ref.Tags {
+			if strings.HasPrefix(tag, "x_") {
+				continue
+			}
+
+			words := strings.Split(tag, "-")
+
+			for i, word := range words {
+				if len(word) != 0 {
+					words[i] = strings.ToUpper(word[:1]) + word[1:]
+				}
+			}
+
+			tags = append(tags, strings.Join(words, " "))
+		}
+
+		ent.References = append(ent.References, reference { ref.URL, rec.Metadata.Assigner, tags })
+	}
+
+	for _, aff := range cna.Affected {
+		for _, cpe := range aff.CPEs {
+			ent.Software = append(ent.Software, cpeURI(cpe))
+		}
+
+		for _, ver := range aff.Versions {
+			status := ver.Status
+			if len(status) == 0 {
+				status = aff.DefaultStatus
+			}
+
+			if status != "affected" {
+				continue
+			}
+
+			ent.Products = append(ent.Products, product {
+				Vendor:          aff.Vendor,
+				Product:         aff.Product,
+				Version:         ver.Version,
+				LessThan:        ver.LessThan,
+				LessThanOrEqual: ver.LessThanOrEqual,
+				Status:          status,
+			})
+		}
+
+		if len(aff.Versions) == 0 && aff.DefaultStatus == "affected" {
+			ent.Products = append(ent.Products, product {
+				Vendor:  aff.Vendor,
+				Product: aff.Product,
+				Status:  aff.DefaultStatus,
+			})
+		}
+	}
+
+	return addItem(ent)
+}
+
+// Sets the CVSS v2 base metrics of the entry. The JSON feeds have no
+// generation date for the metrics, so the modification date is used.
+func (ent *item) setBaseMetrics(v2 *cvssData, source string, date string) {
+	ent.Classification.Severity              = v2.BaseScore
+	ent.Classification.AccessVector          = v2.AccessVector
+	ent.Classification.AccessComplexity      = v2.AccessComplexity
+	ent.Classification.Authentication        = v2.Authentication
+	ent.Classification.ConfidentialityImpact = v2.ConfidentialityImpact
+	ent.Classification.IntegrityImpact       = v2.IntegrityImpact
+	ent.Classification.AvailablityImpact     = v2.AvailabilityImpact
+	ent.Classification.Source                = source
+	ent.Classification.Generated             = date
+}
+
+// Returns the metric provided by the primary source, which is NVD for
+// analyzed entries, or the first one if there is no primary metric.
+func primaryMetric(lst []nvdMetric) *nvdMetric {
+	for i := range lst {
+		if lst[i].Type == "Primary" {
+			return &lst[i]
+		}
+	}
+
+	if len(lst) > 0 {
+		return &lst[0]
+	}
+
+	return nil
+}
+
+// Collects the vulnerable CPE names from the configuration nodes, converted
+// to the CPE 2.2 URI form used by the XML feeds. Matches with version bounds
+// are collected as ranges instead, since their CPE names have no version.
+func (ent *item) collectMatches(nodes []nvdNode) {
+	for _, node := range nodes {
+		for _, match := range append(node.Matches, node.CPEMatch...) {
+			if !match.Vulnerable {
+				continue
+			}
+
+			cpe := cpeURI(match.URI + match.Criteria)
+
+			if len(match.StartIncluding + match.StartExcluding + match.EndIncluding + match.EndExcluding) != 0 {
+				ent.Ranges = append(ent.Ranges, versionRange {
+					CPE:            cpe,
+					StartIncluding: match.StartIncluding,
+					StartExcluding: match.StartExcluding,
+					EndIncluding:   match.EndIncluding,
+					EndExcluding:   match.EndExcluding,
+				})
+
+				continue
+			}
+
+			found := false
+
+			for _, prev := range ent.Software {
+				if prev == cpe {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				ent.Software = append(ent.Software, cpe)
+			}
+		}
+
+		ent.collectMatches(node.Children)
+	}
+}
+
+// Converts a configuration node of the JSON feeds.
+func convertNode(node *nvdNode) *config {
+	conf := &config {
+		Operator: node.Operator,
+		Negate:   node.Negate,
+	}
+
+	for i := range node.Children {
+		conf.Children = append(conf.Children, convertNode(&node.Children[i]))
+	}
+
+	for _, match := range append(node.Matches, node.CPEMatch...) {
+		conf.Matches = append(conf.Matches, configMatch {
+			versionRange: versionRange {
+				CPE:            cpeURI(match.URI + match.Criteria),
+				StartIncluding: match.StartIncluding,
+				StartExcluding: match.StartExcluding,
+				EndIncluding:   match.EndIncluding,
+				EndExcluding:   match.EndExcluding,
+			},
+			Vulnerable: match.Vulnerable,
+		})
+	}
+
+	return conf
+}
+
+// Converts a CPE 2.3 formatted string to a CPE 2.2 URI, such as
+// `cpe:2.3:a:nginx:nginx:1.9.5:*:*:*:*:*:*:*` to `cpe:/a:nginx:nginx:1.9.5`.
+// Extended attributes are packed into the edition field, as per the spec.
+func cpeURI(fs string) string {
+	if !strings.HasPrefix(fs, "cpe:2.3:") {
+		return fs
+	}
+
+	// split on unescaped colons
+
+	var elems []string
+	var cur []byte
+
+	for i := 8; i < len(fs); i++ {
+		if fs[i] == '\\' && i + 1 < len(fs) {
+			i++
+			cur = append(cur, uriChar(fs[i])...)
+		} else if fs[i] == ':' {
+			elems = append(elems, string(cur))
+			cur = cur[:0]
+		} else {
+			cur = append(cur, fs[i])
+		}
+	}
+
+	elems = append(elems, string(cur))
+
+	for i := range elems {
+		if elems[i] == "*" {
+			elems[i] = ""
+		}
+	}
+
+	for len(elems) < 11 {
+		elems = append(elems, "")
+	}
+
+	// part, vendor, product, version, update, edition, language
+	uri := elems[:7]
+
+	if ext := elems[7:11]; len(strings.Join(ext, "")) != 0 {
+		uri[5] = "~" + uri[5] + "~" + strings.Join(ext, "~")
+	}
+
+	for len(uri) > 0 && len(uri[len(uri) - 1]) == 0 {
+		uri = uri[:len(uri) - 1]
+	}
+
+	return "cpe:/" + strings.Join(uri, ":")
+}
+
+// Returns the URI binding of a quoted character of a formatted string, which is
+// percent-encoded unless it is alphanumeric, `.`, `-` or `_`, so quoted colons
+// do not separate the components of the URI.
+func uriChar(c byte) string {
+	if c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '.' || c == '-' || c == '_' {
+		return string(c)
+	}
+
+	return fmt.Sprintf("%%%02x", c)
+}
+
+// Splits the specified CPE URI, such as `cpe:/a:foo%3abar:baz`, into its
+// components, decoding the percent-encoded characters of each.
+func splitCPE(cpe string) []string {
+	elems := strings.Split(strings.TrimPrefix(cpe, "cpe:/"), ":")
+
+	for i, elem := range elems {
+		if dec, err := url.PathUnescape(elem); err == nil {
+			elems[i] = dec
+		}
+	}
+
+	return elems
+}
+
+// Parses the date formats used by the various NVD feeds.
+func parseDate(date string) time.Time {
+	for _, layout := range []string { time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02T15:04:05", "2006-01-02" } {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// Returns the first letter of the access vector, see README for the values.
+func accessVector(vector string) string {
+	switch strings.ToLower(vector) {
+	case "":
+		return ""
+	case "physical":
+		return "l"
+	default:
+		return strings.ToLower(vector)[:1]
+	}
+}
+
+// Returns the first letter of a CVSS v2 metric value, such as `p` for
+// `PARTIAL`, or nil if the value is missing.
+func metricValue(value string) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+
+	return strings.ToLower(value)[:1]
+}
+
+// Builds the CVSS v2 vector string of the entry, such as `AV:N/AC:L/Au:N/C:P/I:P/A:P`,
+// or returns nil if any of the base metrics are missing.
+func (ent *item) vectorString() interface{} {
+	cls := &ent.Classification
+	vec := ""
+
+	for i, value := range []string { cls.AccessVector, cls.AccessComplexity, cls.Authentication, cls.ConfidentialityImpact, cls.IntegrityImpact, cls.AvailablityImpact } {
+		if len(value) == 0 {
+			return nil
+		}
+
+		if i > 0 {
+			vec += "/"
+		}
+
+		vec += []string { "AV", "AC", "Au", "C", "I", "A" }[i] + ":" + strings.ToUpper(value[:1])
+	}
+
+	return vec
+}
+
+// Returns nil for zero values, in order to store them as NULL.
+func nullFloat(v float64) interface{} {
+	if v == 0 {
+		return nil
+	}
+
+	return v
+}
+
+// Returns nil for empty strings, in order to store them as NULL.
+func nullString(v string) interface{} {
+	if len(v) == 0 {
+		return nil
+	}
+
+	return v
+}
+
+// Statements upgrading the schema of the database, where the ones at index i
+// upgrade it from revision i to i + 1. The revision of the database is stored
+// in the `meta` table, so when new columns or tables are added in a new revision,
+// older databases are upgraded in place before being updated.
+var migrations = [][]string {
+	// revision 1: tables of the databases built before revisions were tracked
+	{
+		`create table if not exists vulns (id int not null, cve text, date int, descr text, severity real, access char(1), severity_v3 real, severity_v4 real, unenriched boolean, complexity char(1), authentication char(1), confidentiality char(1), integrity char(1), availability char(1), vector text, cvss_source text, cvss_date int, primary key(id))`,
+		`create table if not exists affected (vuln_id int not null, cpe text, foreign key(vuln_id) references vulns(id))`,
+		`create index if not exists cpe_vuln_idx on affected (cpe collate nocase)`,
+		`create table if not exists cna_affected (vuln_id int not null, vendor text, product text, version text, less_than text, less_than_or_equal text, status text, foreign key(vuln_id) references vulns(id))`,
+		`create index if not exists product_vuln_idx on cna_affected (vendor collate nocase, product collate nocase)`,
+		`create table if not exists ranges (vuln_id int not null, cpe text, vendor text, product text, start_including text, start_excluding text, end_including text, end_excluding text, foreign key(vuln_id) references vulns(id))`,
+		`create index if not exists range_vuln_idx on ranges (vendor collate nocase, product collate nocase)`,
+		`create table if not exists configs (id int not null, vuln_id int not null, parent_id int, operator text, negate boolean, primary key(id), foreign key(vuln_id) references vulns(id))`,
+		`create table if not exists config_matches (config_id int not null, cpe text, vulnerable boolean, start_including text, start_excluding text, end_including text, end_excluding text, foreign key(config_id) references configs(id))`,
+		`create index if not exists config_vuln_idx on configs (vuln_id)`,
+		`create index if not exists match_config_idx on config_matches (config_id)`,
+		`create table if not exists weaknesses (vuln_id int not null, cwe text, foreign key(vuln_id) references vulns(id))`,
+		`create index if not exists cwe_vuln_idx on weaknesses (cwe)`,
+		`create table if not exists cwe (id text not null, name text, description text, parent text, primary key(id))`,
+		`create table if not exists refs (vuln_id int not null, url text, source text, tags text, foreign key(vuln_id) references vulns(id))`,
+		`create index if not exists ref_vuln_idx on refs (vuln_id)`,
+	},
+
+	// revision 2: input files of each build and update
+	{
+		`create table sources (name text, timestamp text, sha256 text, imported text)`,
+	},
+
+	// revision 3: components of the CPE names in affected, see fillAffected
+	{
+		`alter table affected add column part char(1)`,
+		`alter table affected add column vendor text`,
+		`alter table affected add column product text`,
+		`alter table affected add column version text`,
+		`alter table affected add column upd text`,
+		`alter table affected add column edition text`,
+		`alter table affected add column language text`,
+		`alter table affected add column version_key text`,
+		`create index affected_product_idx on affected (vendor collate nocase, product collate nocase, version_key)`,
+	},
+
+	// revision 4: status and modification date of the entries
+	{
+		`alter table vulns add column status text`,
+		`alter table vulns add column modified int`,
+		`update vulns set status = case when descr like '** REJECT **%' then 'rejected' when descr like '** DISPUTED **%' then 'disputed' when descr like '** RESERVED **%' then 'reserved' else 'active' end`,
+		`create index vuln_status_idx on vulns (status)`,
+	},
+
+	// revision 5: canonical product names, see the --aliases argument
+	{
+		`create table aliases (cpe text not null, canonical text, primary key(cpe))`,
+		`alter table affected add column canonical text`,
+		`alter table ranges add column canonical text`,
+		`update affected set canonical = part || ':' || vendor || ':' || product`,
+		`update ranges set canonical = substr(cpe, 1, 1) || ':' || vendor || ':' || product`,
+		`create index affected_canonical_idx on affected (canonical collate nocase, version_key)`,
+		`create index range_canonical_idx on ranges (canonical collate nocase)`,
+	},
+
+	// revision 6: version ranges inferred from the summaries, see inferRanges
+	{
+		`create table inferred_ranges (vuln_id int not null, cpe text, vendor text, product text, start_including text, start_excluding text, end_including text, end_excluding text, canonical text, confidence real, phrase text, foreign key(vuln_id) references vulns(id))`,
+		`create index inferred_canonical_idx on inferred_ranges (canonical collate nocase)`,
+	},
+
+	// revision 7: version keys sorting the same way as cvedb.CompareVersions, see fillVersionKeys
+	{ },
+}
+
+// Functions populating the columns added by a revision from the existing rows,
+// run in order after the statements of the revision.
+var backfills = map[int][]func(tx *sql.Tx) error {
+	1: { fillVulns, fillKeys },
+	3: { fillAffected },
+	7: { fillVersionKeys },
+}
+
+// Writes the globally loaded entries to the specified file, for debugging.
+func serializeEntries(file string) error {
+	// the files are parsed concurrently, so the order is not deterministic
+
+	sort.Slice(entries.Items, func(i, j int) bool {
+		a, _ := cvedb.Key(entries.Items[i].Name)
+		b, _ := cvedb.Key(entries.Items[j].Name)
+		return a < b
+	})
+
+	return hsformat.WriteJSON(file, entries)
+}
+
+// Opens the specified database, creating its tables if needed, and prepares
+// the statements used to write the entries. When updating an existing database,
+// the entries replace the ones with the same CVE ID. The full-text index is
+// created when requested, or kept up to date when the database already has one.
+// The affected software of rejected entries is only written when requested.
+func openDatabase(file string, update bool, fts bool, rejected bool) (*database, error) {
+	var err error
+
+	if update {
+		if _, err = os.Stat(file); err != nil {
+			return nil, err
+		}
+	}
+
+	if !update {
+		os.Remove(file)
+	}
+
+	out := &database { update: update, fts: fts, rejected: rejected }
+
+	if out.db, err = sql.Open("sqlite3", file); err != nil {
+		return nil, err
+	}
+
+	if err = migrate(out.db); err != nil {
+		out.db.Close()
+		return nil, err
+	}
+
+	var indexed bool
+	out.db.QueryRow(`select count(*) > 0 from sqlite_master where name = 'vulns_fts'`).Scan(&indexed)
+
+	if update && !fts {
+		out.fts = indexed
+	}
+
+	if out.fts && !indexed {
+		if _, err = out.db.Exec(`create virtual table vulns_fts using fts5 (cve, descr, products)`); err != nil {
+			out.db.Close()
+			return nil, errors.New(err.Error() + ", run with -tags sqlite_fts5")
+		}
+
+		// when added to an existing database, the entries already stored
+		// are indexed, not just the ones being updated
+
+		if update {
+			if err = fillIndex(out.db); err != nil {
+				out.db.Close()
+				return nil, err
+			}
+		}
+	}
+
+	if out.tx, err = out.db.Begin(); err != nil {
+		out.db.Close()
+		return nil, err
+	}
+
+	// preparing stops at the first error, and the statements prepared before
+	// it are closed along with the transaction when it is rolled back
+
+	prepare := func(stm **sql.Stmt, query string) {
+		if err == nil {
+			*stm, err = out.tx.Prepare(query)
+		}
+	}
+
+	prepare(&out.stm1, "insert into vulns values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	prepare(&out.stm2, "insert into affected values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	prepare(&out.stm3, "insert into cna_affected values (?, ?, ?, ?, ?, ?, ?)")
+	prepare(&out.stm4, "insert into ranges values (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	prepare(&out.stm5, "insert into configs values (?, ?, ?, ?, ?)")
+	prepare(&out.stm6, "insert into config_matches values (?, ?, ?, ?, ?, ?, ?)")
+	prepare(&out.stm7, "insert into weaknesses values (?, ?)")
+	prepare(&out.stm8, "insert or replace into cwe values (?, ?, ?, ?)")
+	prepare(&out.stm9, "insert into refs values (?, ?, ?, ?)")
+	prepare(&out.stm11, "insert into inferred_ranges values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+
+	if out.fts {
+		prepare(&out.stm10, "insert into vulns_fts (rowid, cve, descr, products) values (?, ?, ?, ?)")
+	}
+
+	if err != nil {
+		out.tx.Rollback()
+		out.db.Close()
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Indexes the entries stored in the database in the full-text index, along with
+// the vendor and product names of their affected software, the same way as the
+// entries are indexed when written.
+func fillIndex(db *sql.DB) error {
+	_, err := db.Exec(`insert into vulns_fts (rowid, cve, descr, products)
+		select id, cve, descr, (select group_concat(name, ' ') from (
+			select vendor || ' ' || product as name from affected where vuln_id = vulns.id and part in ('a', 'o')
+			union select vendor || ' ' || product from ranges where vuln_id = vulns.id and substr(cpe, 1, 1) in ('a', 'o')
+			union select vendor || ' ' || product from cna_affected where vuln_id = vulns.id
+		) where length(trim(name)) != 0) from vulns`)
+
+	return err
+}
+
+// Creates the tables of the database, or upgrades them to the latest revision.
+func migrate(db *sql.DB) error {
+	var err error
+	var rev int
+
+	if _, err = db.Exec(`create table if not exists meta (key text not null, value text, primary key(key))`); err != nil {
+		return err
+	}
+
+	db.QueryRow(`select value from meta where key = 'schema_version'`).Scan(&rev)
+
+	if rev > len(migrations) {
+		return fmt.Errorf("database schema revision %d is newer than the supported %d", rev, len(migrations))
+	}
+
+	for ; rev < len(migrations); rev++ {
+		var tx *sql.Tx
+
+		if tx, err = db.Begin(); err != nil {
+			return err
+		}
+
+		for _, stmt := range migrations[rev] {
+			if _, err = tx.Exec(stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("schema revision %d: %s", rev + 1, err.Error())
+			}
+		}
+
+		for _, fill := range backfills[rev + 1] {
+			if err = fill(tx); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("schema revision %d: %s", rev + 1, err.Error())
+			}
+		}
+
+		if _, err = tx.Exec(`insert or replace into meta values ('schema_version', ?)`, rev + 1); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Columns of the `vulns` table as of revision 1, in the order of the table.
+var vulnsColumns = []string {
+	"id int", "cve text", "date int", "descr text", "severity real", "access char(1)",
+	"severity_v3 real", "severity_v4 real", "unenriched boolean",
+	"complexity char(1)", "authentication char(1)", "confidentiality char(1)", "integrity char(1)", "availability char(1)",
+	"vector text", "cvss_source text", "cvss_date int",
+}
+
+// Adds the columns missing from the `vulns` table of databases built before
+// revisions were tracked, which only have a leading part of the columns, as the
+// entries are inserted by position. Tables with other columns are refused.
+func fillVulns(tx *sql.Tx) error {
+	var err error
+	var cols []string
+
+	rows, err := tx.Query(`pragma table_info(vulns)`)
+
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var cid, pk  int
+		var name, typ string
+		var notnull  bool
+		var dflt     sql.NullString
+
+		if err = rows.Scan(&cid, &name, &typ, &notnull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+
+		cols = append(cols, name)
+	}
+
+	rows.Close()
+
+	if len(cols) > len(vulnsColumns) {
+		return fmt.Errorf("vulns table has %d columns instead of %d, the database needs to be rebuilt", len(cols), len(vulnsColumns))
+	}
+
+	for i, name := range cols {
+		if !strings.HasPrefix(vulnsColumns[i], name + " ") {
+			return fmt.Errorf("vulns table has unexpected column %s, the database needs to be rebuilt", name)
+		}
+	}
+
+	for _, col := range vulnsColumns[len(cols):] {
+		if _, err = tx.Exec(`alter table vulns add column ` + col); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Tables referencing the entries by their key in the `vuln_id` column.
+var vulnTables = []string { "affected", "cna_affected", "ranges", "inferred_ranges", "configs", "weaknesses", "refs" }
+
+// Replaces the keys of the entries in databases built before they were derived
+// from the CVE numbers, which were the indices of the entries in the feeds, along
+// with the references to them. Entries without a valid CVE number are deleted,
+// and so are the earlier ones of a CVE number stored more than once.
+func fillKeys(tx *sql.Tx) error {
+	var err error
+	var drop []int64
+
+	keys := make(map[int64]int64)
+	olds := make(map[int64]int64)
+
+	rows, err := tx.Query(`select id, cve from vulns order by id`)
+
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var id  int64
+		var cve string
+
+		if err = rows.Scan(&id, &cve); err != nil {
+			rows.Close()
+			return err
+		}
+
+		key, err := cvedb.Key(cve)
+
+		if err != nil {
+			drop = append(drop, id)
+			continue
+		}
+
+		if old, ok := olds[key]; ok {
+			drop = append(drop, old)
+			delete(keys, old)
+		}
+
+		olds[key] = id
+
+		if key != id {
+			keys[id] = key
+		}
+	}
+
+	rows.Close()
+
+	// only the tables of revision 1 exist at this point
+
+	var fts bool
+	var tables []string
+
+	for _, table := range vulnTables {
+		var found bool
+		tx.QueryRow(`select count(*) > 0 from sqlite_master where type = 'table' and name = ?`, table).Scan(&found)
+
+		if found {
+			tables = append(tables, table)
+		}
+	}
+
+	tx.QueryRow(`select count(*) > 0 from sqlite_master where name = 'vulns_fts'`).Scan(&fts)
+
+	for _, id := range drop {
+		if err = deleteVuln(tx, id, tables, fts); err != nil {
+			return err
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if _, err = tx.Exec(`create temp table rekeys (old int not null, new int not null, primary key(old))`); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`insert into rekeys values (?, ?)`)
+
+	if err != nil {
+		return err
+	}
+
+	for old, key := range keys {
+		if _, err = stmt.Exec(old, key); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
 
-	if fp, err = os.Open(file); err != nil {
-		return err
+	stmt.Close()
+
+	// the keys are negated first, so they do not collide with the ones of
+	// the entries which were not replaced yet
+
+	stmts := []string {
+		`update vulns set id = -(select new from rekeys where old = id) where id in (select old from rekeys)`,
+		`update vulns set id = -id where id < 0`,
 	}
 
-	defer fp.Close()
+	for _, table := range tables {
+		stmts = append(stmts, `update ` + table + ` set vuln_id = (select new from rekeys where old = vuln_id) where vuln_id in (select old from rekeys)`)
+	}
+
+	if fts {
+		stmts = append(stmts, `update vulns_fts set rowid = (select new from rekeys where old = rowid) where rowid in (select old from rekeys)`)
+	}
 
-	txt, _ := ioutil.ReadAll(fp)
+	stmts = append(stmts, `drop table rekeys`)
 
-	if err = xml.Unmarshal(txt, &entries); err != nil {
-		return err
+	for _, stmt := range stmts {
+		if _, err = tx.Exec(stmt); err != nil {
+			return err
+		}
 	}
 
-	return err
+	return nil
 }
 
-// Writes the globally loaded entries to the specified file.
-func serializeEntries(file string, debug bool) error {
+// Splits the CPE names of the existing rows in `affected` into their components.
+func fillAffected(tx *sql.Tx) error {
 	var err error
+	var ids []int64
+	var cpes []string
+
+	rows, err := tx.Query(`select rowid, cpe from affected`)
 
-	if debug {
-		var fp *os.File
+	if err != nil {
+		return err
+	}
 
-		if fp, err = os.Create(file); err != nil {
+	for rows.Next() {
+		var id  int64
+		var cpe string
+
+		if err = rows.Scan(&id, &cpe); err != nil {
+			rows.Close()
 			return err
 		}
 
-		defer fp.Close()
-
-		bw := bufio.NewWriter(fp)
+		ids = append(ids, id)
+		cpes = append(cpes, cpe)
+	}
 
-		var bs []byte
-		bs, err = json.MarshalIndent(entries, "", "\t")
+	rows.Close()
 
-		bw.Write(bs)
-		bw.Flush()
+	stmt, err := tx.Prepare(`update affected set part = ?, vendor = ?, product = ?, version = ?, upd = ?, edition = ?, language = ?, version_key = ? where rowid = ?`)
 
+	if err != nil {
 		return err
 	}
 
-	var db *sql.DB
-	var tx *sql.Tx
-	var stm1, stm2 *sql.Stmt
+	defer stmt.Close()
+
+	for i, cpe := range cpes {
+		if _, err = stmt.Exec(append(cpeColumns(strings.Split(cpe, ":")), ids[i])...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Recomputes the sort keys of the versions of the existing rows in `affected`,
+// since the keys computed before revision 7 did not sort pre-releases and
+// patch letters the same way as the versions are compared.
+func fillVersionKeys(tx *sql.Tx) error {
+	var err error
+	var ids []int64
+	var vers []string
+
+	rows, err := tx.Query(`select rowid, version from affected where version_key is not null`)
 
-	if db, err = sql.Open("sqlite3", file); err != nil {
+	if err != nil {
 		return err
 	}
 
-	defer db.Close()
+	for rows.Next() {
+		var id  int64
+		var ver string
+
+		if err = rows.Scan(&id, &ver); err != nil {
+			rows.Close()
+			return err
+		}
+
+		ids = append(ids, id)
+		vers = append(vers, ver)
+	}
+
+	rows.Close()
 
-	db.Exec(`create table vulns (id int not null, cve text, date int, descr text, severity real, access char(1), primary key(id))`)
-	db.Exec(`create table affected (vuln_id int not null, cpe text, foreign key(vuln_id) references vulns(id))`)
-	db.Exec(`create index cpe_vuln_idx on affected (cpe collate nocase)`)
+	stmt, err := tx.Prepare(`update affected set version_key = ? where rowid = ?`)
 
-	if tx, err = db.Begin(); err != nil {
+	if err != nil {
 		return err
 	}
 
-	defer tx.Commit()
+	defer stmt.Close()
+
+	for i, ver := range vers {
+		if _, err = stmt.Exec(cvedb.VersionKey(ver), ids[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the part, vendor, product, version, update, edition and language
+// components of the specified CPE name split into its components, such as
+// `a`, `apache`, `http_server` and `2.4.7`, followed by the sort key of the
+// version.
+func cpeColumns(elems []string) []interface{} {
+	for len(elems) < 7 {
+		elems = append(elems, "")
+	}
 
-	stm1, _ = tx.Prepare("insert into vulns values (?, ?, ?, ?, ?, ?)")
-	stm2, _ = tx.Prepare("insert into affected values (?, ?)")
+	cols := make([]interface{}, 8)
+	for i := 0; i < 7; i++ {
+		cols[i] = elems[i]
+	}
 
-	defer stm1.Close()
-	defer stm2.Close()
+	if len(elems[3]) != 0 && elems[3] != "-" {
+		cols[7] = cvedb.VersionKey(elems[3])
+	}
 
-	for id, entry := range entries.Items {
-		vs := 0
-		for _, cpe := range entry.Software {
-			if strings.HasPrefix(cpe, "cpe:/a:") || strings.HasPrefix(cpe, "cpe:/o:") {
-				vs++
-				break
+	return cols
+}
+
+// Writes the specified entry to the database. The rows of the entry with the
+// same CVE ID are deleted first when the entry replaces one added previously
+// from another input, or one stored in the database being updated. Rejected entries are stored without
+// their affected software, unless requested otherwise.
+func (out *database) write(entry *item, replace bool) error {
+	var err error
+	var id  int64
+
+	if id, err = cvedb.Key(entry.Name); err != nil {
+		println("Skipping " + entry.Name + ": " + err.Error())
+		return nil
+	}
+
+	if replace {
+		if err = out.deleteEntry(id); err != nil {
+			return err
+		}
+	}
+
+	rejected := entry.Status == "rejected"
+
+	vs := 0
+	for _, cpe := range entry.Software {
+		if strings.HasPrefix(cpe, "cpe:/a:") || strings.HasPrefix(cpe, "cpe:/o:") {
+			vs++
+			break
+		}
+	}
+
+	for _, rng := range entry.Ranges {
+		if strings.HasPrefix(rng.CPE, "cpe:/a:") || strings.HasPrefix(rng.CPE, "cpe:/o:") {
+			vs++
+			break
+		}
+	}
+
+	if vs == 0 && len(entry.Products) == 0 && len(entry.Inferred) == 0 && !rejected {
+		return nil
+	}
+
+	unixtime := parseDate(entry.Date).Unix()
+
+	var modified interface{}
+
+	if len(entry.Modified) != 0 {
+		modified = parseDate(entry.Modified).Unix()
+	}
+
+	cls := &entry.Classification
+	var cvssdate interface{}
+
+	if len(cls.Generated) != 0 {
+		cvssdate = parseDate(cls.Generated).Unix()
+	}
+
+	if _, err = out.stm1.Exec(id, entry.Name[4:], unixtime, entry.Summary, cls.Severity, accessVector(cls.AccessVector), nullFloat(entry.SeverityV3), nullFloat(entry.SeverityV4), entry.Unenriched, metricValue(cls.AccessComplexity), metricValue(cls.Authentication), metricValue(cls.ConfidentialityImpact), metricValue(cls.IntegrityImpact), metricValue(cls.AvailablityImpact), entry.vectorString(), nullString(cls.Source), cvssdate, entry.Status, modified); err != nil {
+		fmt.Printf("%#v\n", err);
+		return nil
+	}
+
+	if out.fts {
+		if _, err = out.stm10.Exec(id, entry.Name[4:], entry.Summary, strings.Join(entry.productNames(), " ")); err != nil {
+			fmt.Printf("%#v\n", err);
+		}
+	}
+
+	// the affected software of rejected entries is not written by default,
+	// so they are not reported by the scanner
+
+	software, ranges, configs, products, inferred := entry.Software, entry.Ranges, entry.Configs, entry.Products, entry.Inferred
+
+	if rejected && !out.rejected {
+		software, ranges, configs, products, inferred = nil, nil, nil, nil, nil
+	}
+
+	for _, cpe := range software {
+		if strings.HasPrefix(cpe, "cpe:/a:") || strings.HasPrefix(cpe, "cpe:/o:") {
+			cols := cpeColumns(splitCPE(cpe))
+			cpe, _ = url.QueryUnescape(cpe)
+			canonical := fmt.Sprintf("%s:%s:%s", cols[0], cols[1], cols[2])
+
+			if _, err = out.stm2.Exec(append(append([]interface{} { id, cpe[5:] }, cols...), canonical)...); err != nil {
+				fmt.Printf("%#v\n", err);
+				continue
+			}
+		}
+	}
+
+	for _, rng := range ranges {
+		if strings.HasPrefix(rng.CPE, "cpe:/a:") || strings.HasPrefix(rng.CPE, "cpe:/o:") {
+			cpe, _ := url.QueryUnescape(rng.CPE)
+			elems := splitCPE(rng.CPE)
+
+			for len(elems) < 3 {
+				elems = append(elems, "")
+			}
+
+			if _, err = out.stm4.Exec(id, cpe[5:], elems[1], elems[2], rng.StartIncluding, rng.StartExcluding, rng.EndIncluding, rng.EndExcluding, strings.Join(elems[:3], ":")); err != nil {
+				fmt.Printf("%#v\n", err);
+				continue
 			}
 		}
+	}
+
+	for _, rng := range inferred {
+		elems := strings.Split(rng.CPE[5:], ":")
+
+		if _, err = out.stm11.Exec(id, rng.CPE[5:], elems[1], elems[2], rng.StartIncluding, rng.StartExcluding, rng.EndIncluding, rng.EndExcluding, rng.CPE[5:], rng.Confidence, rng.Phrase); err != nil {
+			fmt.Printf("%#v\n", err);
+			continue
+		}
+	}
+
+	for _, weak := range entry.Weaknesses {
+		if _, err = out.stm7.Exec(id, weak.Name); err != nil {
+			fmt.Printf("%#v\n", err);
+			continue
+		}
+	}
+
+	for _, ref := range entry.References {
+		if _, err = out.stm9.Exec(id, ref.URL, nullString(ref.Source), nullString(strings.Join(ref.Tags, ","))); err != nil {
+			fmt.Printf("%#v\n", err);
+			continue
+		}
+	}
+
+	cid := 0
+
+	for _, conf := range configs {
+		if err = serializeConfig(out.stm5, out.stm6, &cid, id, nil, conf); err != nil {
+			fmt.Printf("%#v\n", err);
+			continue
+		}
+	}
 
-		if vs == 0 {
+	for _, prod := range products {
+		if _, err = out.stm3.Exec(id, prod.Vendor, prod.Product, prod.Version, prod.LessThan, prod.LessThanOrEqual, prod.Status); err != nil {
+			fmt.Printf("%#v\n", err);
 			continue
 		}
+	}
+
+	return nil
+}
+
+// Writes the CWE catalog, the build metadata and the input sources, then commits
+// the changes and closes the database.
+func (out *database) close() error {
+	var err error
 
-		pubdate, _ := time.Parse(time.RFC3339, entry.Date)
-		unixtime := pubdate.Unix()
+	defer out.db.Close()
 
-		if _, err = stm1.Exec(id, entry.Name[4:], unixtime, entry.Summary, entry.Classification.Severity, strings.ToLower(entry.Classification.AccessVector)[:1]); err != nil {
+	for _, cwe := range weaknesses {
+		descr := cwe.Description
+		if len(descr) == 0 {
+			descr = cwe.Summary
+		}
+
+		if _, err = out.stm8.Exec("CWE-" + cwe.ID, cwe.Name, strings.TrimSpace(descr), cwe.parent()); err != nil {
 			fmt.Printf("%#v\n", err);
 			continue
 		}
+	}
+
+	built := time.Now().UTC().Format(time.RFC3339)
+
+	meta := map[string]interface{} {
+		"generator":         "cve2hs",
+		"generator_version": version,
+		"build_time":        built,
+	}
+
+	if !timestamp.IsZero() {
+		meta["feed_timestamp"] = timestamp.UTC().Format(time.RFC3339)
+	}
+
+	for key, value := range meta {
+		if _, err = out.tx.Exec(`insert or replace into meta values (?, ?)`, key, value); err != nil {
+			out.tx.Rollback()
+			return err
+		}
+	}
+
+	if err = out.applyAliases(); err != nil {
+		out.tx.Rollback()
+		return err
+	}
+
+	for _, src := range sources {
+		var stamp interface{}
+
+		if !src.Timestamp.IsZero() {
+			stamp = src.Timestamp.UTC().Format(time.RFC3339)
+		}
+
+		if _, err = out.tx.Exec(`insert into sources values (?, ?, ?, ?)`, src.Name, stamp, nullString(src.Hash), built); err != nil {
+			out.tx.Rollback()
+			return err
+		}
+	}
+
+	for _, stm := range []*sql.Stmt { out.stm1, out.stm2, out.stm3, out.stm4, out.stm5, out.stm6, out.stm7, out.stm8, out.stm9, out.stm10, out.stm11 } {
+		if stm != nil {
+			stm.Close()
+		}
+	}
+
+	if err = out.tx.Commit(); err != nil {
+		return err
+	}
+
+	out.db.Exec(`vacuum;`)
+
+	return nil
+}
+
+// Stores the loaded CPE aliases, if any, and sets the `canonical` fields of the
+// rows in `affected` and `ranges` to the first name of their alias group. This
+// is done after all entries were written, so the rows of previous builds are
+// also updated when the aliases change.
+func (out *database) applyAliases() error {
+	var err error
+
+	stmts := []string {
+		`update affected set canonical = (select a.canonical from aliases a where a.cpe = lower(part || ':' || vendor || ':' || product)) where lower(part || ':' || vendor || ':' || product) in (select cpe from aliases)`,
+		`update ranges set canonical = (select a.canonical from aliases a where a.cpe = lower(substr(cpe, 1, 1) || ':' || vendor || ':' || product)) where lower(substr(cpe, 1, 1) || ':' || vendor || ':' || product) in (select cpe from aliases)`,
+		`update inferred_ranges set canonical = (select a.canonical from aliases a where a.cpe = lower(cpe)) where lower(cpe) in (select cpe from aliases)`,
+	}
+
+	if aliases != nil {
+		reset := []string {
+			`delete from aliases`,
+			`update affected set canonical = part || ':' || vendor || ':' || product`,
+			`update ranges set canonical = substr(cpe, 1, 1) || ':' || vendor || ':' || product`,
+			`update inferred_ranges set canonical = cpe`,
+		}
 
-		for _, cpe := range entry.Software {
-			if strings.HasPrefix(cpe, "cpe:/a:") || strings.HasPrefix(cpe, "cpe:/o:") {
-				cpe, _ = url.QueryUnescape(cpe)
+		for _, stmt := range reset {
+			if _, err = out.tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
 
-				if _, err = stm2.Exec(id, cpe[5:]); err != nil {
-					fmt.Printf("%#v\n", err);
-					continue
+		for _, group := range aliases {
+			for _, cpe := range group[1:] {
+				if _, err = out.tx.Exec(`insert or ignore into aliases values (?, ?)`, strings.ToLower(cpe), group[0]); err != nil {
+					return err
 				}
 			}
 		}
 	}
 
-	tx.Exec(`vacuum;`)
+	for _, stmt := range stmts {
+		if _, err = out.tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the properties of the entry with the specified CVE ID stored in the
+// database being updated, if any. Entries stored before their enrichment was
+// tracked were all read from the NVD feeds.
+func (out *database) stored(name string) (seenItem, bool) {
+	var err error
+	var id  int64
+
+	if id, err = cvedb.Key(name); err != nil {
+		return seenItem { }, false
+	}
+
+	var unenriched sql.NullBool
+	var modified   sql.NullInt64
+
+	if err = out.tx.QueryRow(`select unenriched, modified from vulns where id = ?`, id).Scan(&unenriched, &modified); err != nil {
+		return seenItem { }, false
+	}
+
+	seen := seenItem { Unenriched: unenriched.Bool }
+
+	if modified.Valid {
+		seen.Modified = time.Unix(modified.Int64, 0).UTC()
+	}
+
+	return seen, true
+}
+
+// Deletes the entry with the specified ID along with the rows referencing it.
+func (out *database) deleteEntry(id int64) error {
+	return deleteVuln(out.tx, id, vulnTables, out.fts)
+}
+
+// Deletes the entry with the specified ID along with the rows of the specified
+// tables referencing it, and its row in the full-text index, if requested.
+func deleteVuln(tx *sql.Tx, id int64, tables []string, fts bool) error {
+	stmts := []string {
+		`delete from config_matches where config_id in (select id from configs where vuln_id = ?)`,
+	}
+
+	for _, table := range tables {
+		stmts = append(stmts, `delete from ` + table + ` where vuln_id = ?`)
+	}
+
+	stmts = append(stmts, `delete from vulns where id = ?`)
+
+	if fts {
+		stmts = append(stmts, `delete from vulns_fts where rowid = ?`)
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the distinct vendor and product names of the affected software,
+// such as `apache http_server`, to be indexed along with the summary.
+func (ent *item) productNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	add := func(name string) {
+		if len(strings.TrimSpace(name)) != 0 && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	addCPE := func(cpe string) {
+		elems := splitCPE(cpe)
+
+		if len(elems) >= 3 && (elems[0] == "a" || elems[0] == "o") {
+			add(elems[1] + " " + elems[2])
+		}
+	}
+
+	for _, cpe := range ent.Software {
+		addCPE(cpe)
+	}
+
+	for _, rng := range ent.Ranges {
+		addCPE(rng.CPE)
+	}
+
+	for _, prod := range ent.Products {
+		add(prod.Vendor + " " + prod.Product)
+	}
+
+	return names
+}
+
+// Writes the specified configuration node and its children, assigning them
+// sequential IDs using the specified counter. The IDs are derived from the key
+// of the vulnerability, so they are stable across builds.
+func serializeConfig(nodes, matches *sql.Stmt, cid *int, vid int64, parent interface{}, conf *config) error {
+	var err error
+
+	if *cid >= maxConfigs {
+		return errors.New("too many configuration nodes in CVE " + cvedb.CVE(vid))
+	}
+
+	id := vid * maxConfigs + int64(*cid)
+	*cid++
+
+	op := conf.Operator
+	if len(op) == 0 {
+		op = "OR"
+	}
+
+	if _, err = nodes.Exec(id, vid, parent, op, conf.Negate); err != nil {
+		return err
+	}
+
+	for _, match := range conf.Matches {
+		cpe, _ := url.QueryUnescape(match.CPE)
+
+		if _, err = matches.Exec(id, strings.TrimPrefix(cpe, "cpe:/"), match.Vulnerable, match.StartIncluding, match.StartExcluding, match.EndIncluding, match.EndExcluding); err != nil {
+			return err
+		}
+	}
+
+	for _, child := range conf.Children {
+		if err = serializeConfig(nodes, matches, cid, vid, id, child); err != nil {
+			return err
+		}
+	}
 
 	return err
 }
 
 // Entry point of the application.
 func main() {
+	var err error
+	var dbg bool
+	var upd bool
+	var fts bool
+	var rej bool
+	var cwe string
+	var als string
+	var dic string
+
+	for len(os.Args) > 2 && strings.HasPrefix(os.Args[1], "--") {
+		switch os.Args[1] {
+		case "--json":
+			dbg = true
+		case "--update":
+			upd = true
+		case "--fts":
+			fts = true
+		case "--rejected":
+			rej = true
+		case "--cwe":
+			cwe = os.Args[2]
+			os.Args = os.Args[1:]
+		case "--aliases":
+			als = os.Args[2]
+			os.Args = os.Args[1:]
+		case "--cpe":
+			dic = os.Args[2]
+			os.Args = os.Args[1:]
+		}
+
+		os.Args = os.Args[1:]
+	}
+
 	if len(os.Args) < 3 {
-		println("usage: cve2hs [--json] input output")
+		println("usage: cve2hs [--json] [--update] [--fts] [--rejected] [--cwe catalog] [--aliases file] [--cpe dictionary] input... output")
 		os.Exit(-1)
 	}
 
-	var err error
-	var dbg bool
+	// the output of --json is a new file, which would overwrite the database
 
-	if os.Args[1] == "--json" {
-		dbg = true
-		os.Args = os.Args[1:]
+	if dbg && upd {
+		println("--json cannot be combined with --update")
+		os.Exit(-1)
+	}
+
+	if len(cwe) != 0 {
+		println("Parsing CWE catalog...")
+
+		if err = parseCatalog(cwe); err != nil {
+			println(err.Error())
+			os.Exit(-1)
+		}
+	}
+
+	if len(als) != 0 {
+		println("Parsing CPE aliases...")
+
+		if err = parseAliases(als); err != nil {
+			println(err.Error())
+			os.Exit(-1)
+		}
+	}
+
+	if len(dic) != 0 {
+		println("Parsing CPE dictionary...")
+
+		if err = parseDictionary(dic); err != nil {
+			println(err.Error())
+			os.Exit(-1)
+		}
+	}
+
+	file := os.Args[len(os.Args) - 1]
+
+	if !dbg {
+		if output, err = openDatabase(file, upd, fts, rej); err != nil {
+			println(err.Error())
+			os.Exit(-1)
+		}
 	}
 
 	println("Parsing CVE database...")
 
-	if err = parseInput(os.Args[1]); err != nil {
-		println(err)
+	if err = parseInput(os.Args[1:len(os.Args) - 1]); err != nil {
+		println(err.Error())
 		os.Exit(-1)
 	}
 
 	println("Writing parsed data...")
 
-	if err = serializeEntries(os.Args[2], dbg); err != nil {
-		println(err)
+	if dbg {
+		err = serializeEntries(file)
+	} else {
+		err = output.close()
+	}
+
+	if err != nil {
+		println(err.Error())
 		os.Exit(-1)
 	}
 }
\ No newline at end of file
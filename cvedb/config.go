@@ -0,0 +1,180 @@
+package cvedb
+
+import (
+	"strings"
+	"database/sql"
+)
+
+// Config is a node of a configuration tree, as stored in the `configs` and
+// `config_matches` tables. A node is satisfied if all (AND) or any (OR) of its
+// children and CPE matches are satisfied, inverted if Negate is set.
+type Config struct {
+	Operator string
+	Negate   bool
+	Children []*Config
+	Matches  []*Match
+}
+
+// Match is a CPE name within a configuration node, optionally with a version
+// range. Vulnerable is set for the software affected by the vulnerability, and
+// not set for the platform it has to run on or with.
+type Match struct {
+	Range
+	CPE        string
+	Vulnerable bool
+}
+
+// Evaluate checks whether the configuration is satisfied by the CPE names
+// detected on a host, such as `cpe:/a:mozilla:firefox:49.0`.
+func (c *Config) Evaluate(cpes []string) bool {
+	and := strings.EqualFold(c.Operator, "AND")
+	res := and
+
+	for _, child := range c.Children {
+		if child.Evaluate(cpes) != and {
+			res = !and
+			break
+		}
+	}
+
+	if res == and {
+		for _, match := range c.Matches {
+			if match.Evaluate(cpes) != and {
+				res = !and
+				break
+			}
+		}
+	}
+
+	return res != c.Negate
+}
+
+// Evaluate checks whether any of the specified CPE names matches. Components
+// which are empty or `-` in the match are not compared, and the version is
+// checked against the range, if there is one.
+func (m *Match) Evaluate(cpes []string) bool {
+	want := strings.Split(strings.TrimPrefix(m.CPE, "cpe:/"), ":")
+	rng  := len(m.StartIncluding + m.StartExcluding + m.EndIncluding + m.EndExcluding) != 0
+
+	for _, cpe := range cpes {
+		have := strings.Split(strings.TrimPrefix(cpe, "cpe:/"), ":")
+		ok := true
+
+		for i, comp := range want {
+			if len(comp) == 0 || comp == "-" {
+				continue
+			}
+
+			if i >= len(have) {
+				ok = false
+				break
+			}
+
+			if i == 3 {
+				if CompareVersions(comp, have[i]) != 0 {
+					ok = false
+					break
+				}
+			} else if !strings.EqualFold(comp, have[i]) {
+				ok = false
+				break
+			}
+		}
+
+		if ok && rng && (len(have) < 4 || !m.Contains(have[3])) {
+			ok = false
+		}
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadConfigs loads the configuration trees of the specified CVE number,
+// such as `CVE-2016-0001` or `2016-0001`.
+func LoadConfigs(db *sql.DB, cve string) ([]*Config, error) {
+	key, err := Key(cve)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`select c.id, c.parent_id, c.operator, c.negate from configs c where c.vuln_id = ? order by c.id`, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []*Config
+	nodes := make(map[int64]*Config)
+
+	for rows.Next() {
+		var id int64
+		var parent sql.NullInt64
+		conf := &Config { }
+
+		if err = rows.Scan(&id, &parent, &conf.Operator, &conf.Negate); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		nodes[id] = conf
+
+		if !parent.Valid {
+			roots = append(roots, conf)
+		} else if p, ok := nodes[parent.Int64]; ok {
+			p.Children = append(p.Children, conf)
+		}
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil || len(nodes) == 0 {
+		return roots, err
+	}
+
+	rows, err = db.Query(`select m.config_id, m.cpe, m.vulnerable, m.start_including, m.start_excluding, m.end_including, m.end_excluding from config_matches m join configs c on c.id = m.config_id where c.vuln_id = ?`, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		match := &Match { }
+
+		if err = rows.Scan(&id, &match.CPE, &match.Vulnerable, &match.StartIncluding, &match.StartExcluding, &match.EndIncluding, &match.EndExcluding); err != nil {
+			return nil, err
+		}
+
+		if conf, ok := nodes[id]; ok {
+			conf.Matches = append(conf.Matches, match)
+		}
+	}
+
+	return roots, rows.Err()
+}
+
+// Applicable checks whether any of the configurations of the specified CVE
+// number is satisfied by the CPE names detected on a host. CVEs without any
+// stored configuration are considered applicable.
+func Applicable(db *sql.DB, cve string, cpes []string) (bool, error) {
+	confs, err := LoadConfigs(db, cve)
+
+	if err != nil || len(confs) == 0 {
+		return err == nil, err
+	}
+
+	for _, conf := range confs {
+		if conf.Evaluate(cpes) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
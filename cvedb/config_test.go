@@ -0,0 +1,90 @@
+package cvedb
+
+import (
+	"testing"
+)
+
+func TestMatchEvaluate(t *testing.T) {
+	tests := []struct {
+		match Match
+		cpes []string
+		want bool
+	}{
+		{ Match { CPE: "cpe:/a:mozilla:firefox:49.0" }, []string{ "cpe:/a:mozilla:firefox:49.0" }, true },
+		{ Match { CPE: "cpe:/a:mozilla:firefox:49.0" }, []string{ "cpe:/a:mozilla:firefox:49.0.0" }, true },
+		{ Match { CPE: "cpe:/a:mozilla:firefox:49.0" }, []string{ "cpe:/a:mozilla:firefox:50.0" }, false },
+		{ Match { CPE: "cpe:/a:mozilla:firefox:49.0" }, []string{ "cpe:/a:mozilla:firefox" }, false },
+		{ Match { CPE: "cpe:/a:mozilla:firefox:49.0" }, []string{ "cpe:/a:google:chrome:53.0", "cpe:/a:mozilla:firefox:49.0" }, true },
+		{ Match { CPE: "cpe:/a:mozilla:firefox:49.0" }, nil, false },
+		{ Match { CPE: "cpe:/a:Mozilla:FireFox:49.0" }, []string{ "cpe:/a:mozilla:firefox:49.0" }, true },
+		{ Match { CPE: "cpe:/a:mozilla:firefox" }, []string{ "cpe:/a:mozilla:firefox:49.0" }, true },
+		{ Match { CPE: "cpe:/a:mozilla:firefox" }, []string{ "cpe:/a:mozilla:thunderbird:49.0" }, false },
+		{ Match { CPE: "cpe:/a:mozilla:firefox" }, []string{ "cpe:/o:mozilla:firefox:49.0" }, false },
+		{ Match { CPE: "cpe:/a:mozilla:firefox:-" }, []string{ "cpe:/a:mozilla:firefox:49.0" }, true },
+		{ Match { CPE: "cpe:/o:microsoft:windows_10:-:-:x64" }, []string{ "cpe:/o:microsoft:windows_10:1607::x64" }, true },
+		{ Match { CPE: "cpe:/o:microsoft:windows_10:-:-:x64" }, []string{ "cpe:/o:microsoft:windows_10:1607::x86" }, false },
+		{ Match { CPE: "cpe:/o:microsoft:windows_10:-:-:x64" }, []string{ "cpe:/o:microsoft:windows_10:1607" }, false },
+		{ Match { CPE: "cpe:/a:nginx:nginx", Range: Range { EndExcluding: "1.9.5" } }, []string{ "cpe:/a:nginx:nginx:1.9.4" }, true },
+		{ Match { CPE: "cpe:/a:nginx:nginx", Range: Range { EndExcluding: "1.9.5" } }, []string{ "cpe:/a:nginx:nginx:1.9.5" }, false },
+		{ Match { CPE: "cpe:/a:nginx:nginx", Range: Range { EndExcluding: "1.9.5" } }, []string{ "cpe:/a:nginx:nginx" }, false },
+		{ Match { CPE: "cpe:/a:nginx:nginx", Range: Range { EndExcluding: "1.9.5" } }, []string{ "cpe:/a:nginx:nginx:1.10.0", "cpe:/a:nginx:nginx:1.8.1" }, true },
+		{ Match { CPE: "cpe:/a:openssl:openssl", Range: Range { StartIncluding: "1.0.1", EndExcluding: "1.0.1g" } }, []string{ "cpe:/a:openssl:openssl:1.0.1f" }, true },
+		{ Match { CPE: "cpe:/a:openssl:openssl", Range: Range { StartIncluding: "1.0.1", EndExcluding: "1.0.1g" } }, []string{ "cpe:/a:openssl:openssl:1.0.0t" }, false },
+		{ Match { CPE: "cpe:/a:openssl:openssl:-", Range: Range { StartExcluding: "1.0.1", EndIncluding: "1.0.2" } }, []string{ "cpe:/a:openssl:openssl:1.0.2" }, true },
+		{ Match { CPE: "cpe:/a:openssl:openssl:-", Range: Range { StartExcluding: "1.0.1", EndIncluding: "1.0.2" } }, []string{ "cpe:/a:openssl:openssl:1.0.1" }, false },
+	}
+
+	for _, test := range tests {
+		if got := test.match.Evaluate(test.cpes); got != test.want {
+			t.Errorf("%+v.Evaluate(%q) = %v, want %v", test.match, test.cpes, got, test.want)
+		}
+	}
+}
+
+func TestConfigEvaluate(t *testing.T) {
+	firefox := &Match { CPE: "cpe:/a:mozilla:firefox", Range: Range { EndExcluding: "50.0" }, Vulnerable: true }
+	chrome  := &Match { CPE: "cpe:/a:google:chrome", Vulnerable: true }
+	windows := &Match { CPE: "cpe:/o:microsoft:windows" }
+	linux   := &Match { CPE: "cpe:/o:linux:linux_kernel" }
+
+	// firefox or chrome, running on windows
+
+	browser := &Config {
+		Operator: "AND",
+		Children: []*Config {
+			{ Operator: "OR", Matches: []*Match { firefox, chrome } },
+			{ Operator: "OR", Matches: []*Match { windows } },
+		},
+	}
+
+	tests := []struct {
+		name string
+		conf *Config
+		cpes []string
+		want bool
+	}{
+		{ "or", &Config { Operator: "OR", Matches: []*Match { firefox, chrome } }, []string{ "cpe:/a:google:chrome:53.0" }, true },
+		{ "or", &Config { Operator: "OR", Matches: []*Match { firefox, chrome } }, []string{ "cpe:/a:mozilla:firefox:50.0" }, false },
+		{ "or", &Config { Operator: "or", Matches: []*Match { firefox, chrome } }, []string{ "cpe:/a:mozilla:firefox:49.0" }, true },
+		{ "and", &Config { Operator: "AND", Matches: []*Match { firefox, windows } }, []string{ "cpe:/a:mozilla:firefox:49.0", "cpe:/o:microsoft:windows:10" }, true },
+		{ "and", &Config { Operator: "AND", Matches: []*Match { firefox, windows } }, []string{ "cpe:/a:mozilla:firefox:49.0" }, false },
+		{ "and", &Config { Operator: "and", Matches: []*Match { firefox, windows } }, []string{ "cpe:/o:microsoft:windows:10" }, false },
+		{ "nested", browser, []string{ "cpe:/a:mozilla:firefox:49.0", "cpe:/o:microsoft:windows:10" }, true },
+		{ "nested", browser, []string{ "cpe:/a:google:chrome:53.0", "cpe:/o:microsoft:windows:10" }, true },
+		{ "nested", browser, []string{ "cpe:/a:mozilla:firefox:49.0", "cpe:/o:linux:linux_kernel:4.8" }, false },
+		{ "nested", browser, []string{ "cpe:/a:mozilla:firefox:50.0", "cpe:/o:microsoft:windows:10" }, false },
+		{ "negate", &Config { Operator: "OR", Negate: true, Matches: []*Match { linux } }, []string{ "cpe:/o:microsoft:windows:10" }, true },
+		{ "negate", &Config { Operator: "OR", Negate: true, Matches: []*Match { linux } }, []string{ "cpe:/o:linux:linux_kernel:4.8" }, false },
+		{ "negate", &Config { Operator: "AND", Children: []*Config { { Operator: "OR", Matches: []*Match { chrome } }, { Operator: "OR", Negate: true, Matches: []*Match { linux } } } }, []string{ "cpe:/a:google:chrome:53.0", "cpe:/o:microsoft:windows:10" }, true },
+		{ "negate", &Config { Operator: "AND", Children: []*Config { { Operator: "OR", Matches: []*Match { chrome } }, { Operator: "OR", Negate: true, Matches: []*Match { linux } } } }, []string{ "cpe:/a:google:chrome:53.0", "cpe:/o:linux:linux_kernel:4.8" }, false },
+		{ "negate", &Config { Operator: "AND", Negate: true, Children: []*Config { browser } }, []string{ "cpe:/a:google:chrome:53.0" }, true },
+		{ "empty", &Config { Operator: "AND" }, nil, true },
+		{ "empty", &Config { Operator: "OR" }, nil, false },
+	}
+
+	for _, test := range tests {
+		if got := test.conf.Evaluate(test.cpes); got != test.want {
+			t.Errorf("%s: Evaluate(%q) = %v, want %v", test.name, test.cpes, got, test.want)
+		}
+	}
+}
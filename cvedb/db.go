@@ -0,0 +1,58 @@
+package cvedb
+
+import (
+	"os"
+	"time"
+	"errors"
+	"database/sql"
+)
+
+// ErrNoVulns is returned by OpenDatabase when the database was not written by cve2hs.
+var ErrNoVulns = errors.New("no vulns table in database, run cve2hs first")
+
+// OpenDatabase opens the existing CVE database written by cve2hs, in order to
+// add the tables of another source to it, which are created by the specified
+// statements along with the `meta` table, if missing. The sqlite3 driver has
+// to be imported by the caller.
+func OpenDatabase(file string, stmts ...string) (*sql.DB, error) {
+	if _, err := os.Stat(file); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var vulns bool
+	db.QueryRow(`select count(*) > 0 from sqlite_master where type = 'table' and name = 'vulns'`).Scan(&vulns)
+
+	if !vulns {
+		db.Close()
+		return nil, ErrNoVulns
+	}
+
+	stmts = append(stmts, `create table if not exists meta (key text not null, value text, primary key(key))`)
+
+	for _, stmt := range stmts {
+		if _, err = db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+// UnixDate converts the specified `YYYY-MM-DD` date to the unix timestamp stored
+// in the date columns, or nil if it is missing or invalid.
+func UnixDate(date string) interface{} {
+	tm, err := time.Parse("2006-01-02", date)
+
+	if err != nil {
+		return nil
+	}
+
+	return tm.Unix()
+}
@@ -0,0 +1,48 @@
+package cvedb
+
+import (
+	"time"
+	"database/sql"
+)
+
+// EPSSScore is the exploit prediction score of a vulnerability on a given
+// date, as stored in the `epss` table by epss2hs.
+type EPSSScore struct {
+	Date time.Time
+	EPSS, Percentile float64
+}
+
+// EPSS returns the exploit prediction scores of the specified CVE number, such
+// as `CVE-2021-44228`, starting with the latest one. The scores of the earlier
+// dates are kept by epss2hs in order to show the trend of the probability.
+func EPSS(db *sql.DB, cve string) ([]EPSSScore, error) {
+	key, err := Key(cve)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`select score_date, epss, percentile from epss where vuln_id = ? order by score_date desc`, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var scores []EPSSScore
+
+	for rows.Next() {
+		var date  int64
+		var score EPSSScore
+
+		if err = rows.Scan(&date, &score.EPSS, &score.Percentile); err != nil {
+			return nil, err
+		}
+
+		score.Date = time.Unix(date, 0).UTC()
+		scores = append(scores, score)
+	}
+
+	return scores, rows.Err()
+}
@@ -0,0 +1,112 @@
+package cvedb
+
+import (
+	"time"
+	"strings"
+	"database/sql"
+)
+
+// Exploit is a public exploit of a vulnerability, as stored in the `exploits`
+// table by edb2hs.
+type Exploit struct {
+	Source string
+	ID int
+	Title, Type, Platform string
+	Date time.Time
+	Verified bool
+}
+
+// Exploits returns the public exploits of the specified CVE number, such as
+// `CVE-2014-0160`, starting with the latest one.
+func Exploits(db *sql.DB, cve string) ([]Exploit, error) {
+	key, err := Key(cve)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`select source, edb_id, title, type, platform, date, verified from exploits where vuln_id = ? order by date desc`, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var exploits []Exploit
+
+	for rows.Next() {
+		var id   sql.NullInt64
+		var date sql.NullInt64
+		var expl Exploit
+
+		if err = rows.Scan(&expl.Source, &id, &expl.Title, &expl.Type, &expl.Platform, &date, &expl.Verified); err != nil {
+			return nil, err
+		}
+
+		expl.ID = int(id.Int64)
+
+		if date.Valid {
+			expl.Date = time.Unix(date.Int64, 0).UTC()
+		}
+
+		exploits = append(exploits, expl)
+	}
+
+	return exploits, rows.Err()
+}
+
+// Module is a Metasploit module referencing a vulnerability, as stored in the
+// `msf_modules` table by msf2hs.
+type Module struct {
+	Path, Name, Type string
+	Rank int
+	Targets []string
+	Disclosed time.Time
+	Remote bool
+	Reference string
+}
+
+// Modules returns the Metasploit modules of the specified CVE number, such as
+// `CVE-2017-0144`, starting with the highest ranked one. The path of a module,
+// such as `exploit/windows/smb/ms17_010_eternalblue`, can be passed to the `use`
+// command of Metasploit.
+func Modules(db *sql.DB, cve string) ([]Module, error) {
+	key, err := Key(cve)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`select path, name, type, rank, targets, disclosure_date, remote, reference from msf_modules where vuln_id = ? order by rank desc, path`, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var modules []Module
+
+	for rows.Next() {
+		var date    sql.NullInt64
+		var targets string
+		var mod     Module
+
+		if err = rows.Scan(&mod.Path, &mod.Name, &mod.Type, &mod.Rank, &targets, &date, &mod.Remote, &mod.Reference); err != nil {
+			return nil, err
+		}
+
+		if len(targets) != 0 {
+			mod.Targets = strings.Split(targets, "\n")
+		}
+
+		if date.Valid {
+			mod.Disclosed = time.Unix(date.Int64, 0).UTC()
+		}
+
+		modules = append(modules, mod)
+	}
+
+	return modules, rows.Err()
+}
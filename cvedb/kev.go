@@ -0,0 +1,48 @@
+package cvedb
+
+import (
+	"time"
+	"database/sql"
+)
+
+// KEVEntry is an entry of the CISA Known Exploited Vulnerabilities catalog, as
+// stored in the `kev` table by kev2hs.
+type KEVEntry struct {
+	CVE, Vendor, Product, Name string
+	DateAdded, DueDate time.Time
+	Ransomware bool
+	RequiredAction string
+}
+
+// KnownExploited returns the KEV catalog entry of the specified CVE number, such
+// as `CVE-2021-44228`, or nil if it is not known to be exploited.
+func KnownExploited(db *sql.DB, cve string) (*KEVEntry, error) {
+	key, err := Key(cve)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var added, due sql.NullInt64
+	kev := &KEVEntry { CVE: CVE(key) }
+
+	err = db.QueryRow(`select vendor, product, name, date_added, due_date, ransomware, required_action from kev where vuln_id = ?`, key).Scan(&kev.Vendor, &kev.Product, &kev.Name, &added, &due, &kev.Ransomware, &kev.RequiredAction)
+
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return nil, nil
+	default:
+		return nil, err
+	}
+
+	if added.Valid {
+		kev.DateAdded = time.Unix(added.Int64, 0).UTC()
+	}
+
+	if due.Valid {
+		kev.DueDate = time.Unix(due.Int64, 0).UTC()
+	}
+
+	return kev, nil
+}
@@ -0,0 +1,45 @@
+package cvedb
+
+import (
+	"fmt"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCVE is returned when a CVE number is not in the `CVE-YYYY-NNNN` format.
+var ErrInvalidCVE = errors.New("invalid CVE number")
+
+// keyFactor separates the year from the sequence number within a key, and
+// accommodates sequence numbers of up to 8 digits.
+const keyFactor = 100000000
+
+// Key derives the stable key used as `vulns.id` from the specified CVE number,
+// such as `CVE-2014-0160`, which results in `201400000160`. The "CVE-" prefix is
+// optional, as the `vulns` table stores the numbers without it.
+func Key(cve string) (int64, error) {
+	parts := strings.Split(strings.TrimPrefix(cve, "CVE-"), "-")
+
+	if len(parts) != 2 || len(parts[0]) != 4 || len(parts[1]) < 4 || !isNumeric(parts[0]) || !isNumeric(parts[1]) {
+		return 0, ErrInvalidCVE
+	}
+
+	year, err := strconv.ParseInt(parts[0], 10, 64)
+
+	if err != nil {
+		return 0, ErrInvalidCVE
+	}
+
+	seq, err := strconv.ParseInt(parts[1], 10, 64)
+
+	if err != nil || seq < 0 || seq >= keyFactor {
+		return 0, ErrInvalidCVE
+	}
+
+	return year * keyFactor + seq, nil
+}
+
+// CVE returns the CVE number the specified key was derived from.
+func CVE(key int64) string {
+	return fmt.Sprintf("CVE-%04d-%04d", key / keyFactor, key % keyFactor)
+}
@@ -0,0 +1,62 @@
+package cvedb
+
+import (
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		cve string
+		want int64
+		err error
+	}{
+		{ "CVE-2014-0160", 201400000160, nil },
+		{ "2014-0160", 201400000160, nil },
+		{ "CVE-1999-0001", 199900000001, nil },
+		{ "CVE-2021-44228", 202100044228, nil },
+		{ "CVE-2023-1234567", 202301234567, nil },
+		{ "CVE-2023-12345678", 202312345678, nil },
+		{ "CVE-2023-123456789", 0, ErrInvalidCVE },
+		{ "CVE-2014-160", 0, ErrInvalidCVE },
+		{ "CVE-14-0160", 0, ErrInvalidCVE },
+		{ "CVE-2014-0160-1", 0, ErrInvalidCVE },
+		{ "CVE-2014-01a0", 0, ErrInvalidCVE },
+		{ "CVE-20x4-0160", 0, ErrInvalidCVE },
+		{ "CVE-2014--160", 0, ErrInvalidCVE },
+		{ "CVE-2014-+160", 0, ErrInvalidCVE },
+		{ "CVE-+014-0160", 0, ErrInvalidCVE },
+		{ "CVE-2014", 0, ErrInvalidCVE },
+		{ "", 0, ErrInvalidCVE },
+	}
+
+	for _, test := range tests {
+		got, err := Key(test.cve)
+
+		if got != test.want || err != test.err {
+			t.Errorf("Key(%q) = %d, %v, want %d, %v", test.cve, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestCVE(t *testing.T) {
+	tests := []struct {
+		key int64
+		want string
+	}{
+		{ 201400000160, "CVE-2014-0160" },
+		{ 199900000001, "CVE-1999-0001" },
+		{ 202100044228, "CVE-2021-44228" },
+		{ 202301234567, "CVE-2023-1234567" },
+		{ 202312345678, "CVE-2023-12345678" },
+	}
+
+	for _, test := range tests {
+		if got := CVE(test.key); got != test.want {
+			t.Errorf("CVE(%d) = %q, want %q", test.key, got, test.want)
+		}
+
+		if key, err := Key(test.want); key != test.key || err != nil {
+			t.Errorf("Key(CVE(%d)) = %d, %v", test.key, key, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package cvedb
+
+import (
+	"strings"
+	"database/sql"
+)
+
+// Range is a version range of a product, as stored in the `ranges` table.
+// Empty bounds are not checked.
+type Range struct {
+	StartIncluding, StartExcluding string
+	EndIncluding, EndExcluding string
+}
+
+// Contains checks whether the specified version falls within the range.
+func (r *Range) Contains(ver string) bool {
+	if len(r.StartIncluding) != 0 && CompareVersions(ver, r.StartIncluding) < 0 {
+		return false
+	}
+
+	if len(r.StartExcluding) != 0 && CompareVersions(ver, r.StartExcluding) <= 0 {
+		return false
+	}
+
+	if len(r.EndIncluding) != 0 && CompareVersions(ver, r.EndIncluding) > 0 {
+		return false
+	}
+
+	if len(r.EndExcluding) != 0 && CompareVersions(ver, r.EndExcluding) >= 0 {
+		return false
+	}
+
+	return true
+}
+
+// Affected returns the CVE numbers affecting the specified version of the
+// product identified by its CPE name, such as `cpe:/a:nginx:nginx`. Both the
+// CPE names enumerated in the `affected` table and the version ranges in the
+// `ranges` table are checked, the former by the `version_key` column, so equal
+// versions such as 1.9.4 and 1.9.4.0 match. The vulnerabilities filed under the
+// aliases of the product are also returned. Rejected entries are never returned.
+func Affected(db *sql.DB, cpe, version string) ([]string, error) {
+	prod, err := Canonical(db, cpe)
+
+	if len(prod) == 0 || err != nil {
+		return nil, err
+	}
+
+	var cves []string
+	seen := make(map[string]bool)
+
+	add := func(cve string) {
+		if !seen[cve] {
+			seen[cve] = true
+			cves = append(cves, cve)
+		}
+	}
+
+	rows, err := db.Query(`select v.cve from affected a join vulns v on v.id = a.vuln_id where a.canonical = ? collate nocase and (a.version = '' or a.version_key = ?) and v.status != 'rejected'`, prod, VersionKey(version))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var cve string
+
+		if err = rows.Scan(&cve); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		add(cve)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.Query(`select v.cve, r.start_including, r.start_excluding, r.end_including, r.end_excluding from ranges r join vulns v on v.id = r.vuln_id where r.canonical = ? collate nocase and v.status != 'rejected'`, prod)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var cve string
+		var rng Range
+
+		if err = rows.Scan(&cve, &rng.StartIncluding, &rng.StartExcluding, &rng.EndIncluding, &rng.EndExcluding); err != nil {
+			return nil, err
+		}
+
+		if rng.Contains(version) {
+			add(cve)
+		}
+	}
+
+	return cves, rows.Err()
+}
+
+// AffectedBetween returns the CVE numbers which list a version of the product
+// identified by its CPE name, such as `cpe:/a:apache:http_server`, between the
+// specified versions inclusively, in the `affected` table. The vulnerabilities
+// filed under the aliases of the product are also returned. Rejected entries are
+// never returned.
+func AffectedBetween(db *sql.DB, cpe, from, to string) ([]string, error) {
+	prod, err := Canonical(db, cpe)
+
+	if len(prod) == 0 || err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`select distinct v.cve from affected a join vulns v on v.id = a.vuln_id where a.canonical = ? collate nocase and a.version_key between ? and ? and v.status != 'rejected'`, prod, VersionKey(from), VersionKey(to))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var cves []string
+
+	for rows.Next() {
+		var cve string
+
+		if err = rows.Scan(&cve); err != nil {
+			return nil, err
+		}
+
+		cves = append(cves, cve)
+	}
+
+	return cves, rows.Err()
+}
+
+// Inferred returns the CVE numbers whose version ranges inferred from their
+// summaries, as stored in the `inferred_ranges` table, contain the specified
+// version of the product, with a confidence of at least the specified value.
+// These are not returned by Affected, since the ranges may be inaccurate.
+// Rejected entries are never returned.
+func Inferred(db *sql.DB, cpe, version string, confidence float64) ([]string, error) {
+	prod, err := Canonical(db, cpe)
+
+	if len(prod) == 0 || err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`select v.cve, r.start_including, r.start_excluding, r.end_including, r.end_excluding from inferred_ranges r join vulns v on v.id = r.vuln_id where r.canonical = ? collate nocase and r.confidence >= ? and v.status != 'rejected'`, prod, confidence)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var cves []string
+	seen := make(map[string]bool)
+
+	for rows.Next() {
+		var cve string
+		var rng Range
+
+		if err = rows.Scan(&cve, &rng.StartIncluding, &rng.StartExcluding, &rng.EndIncluding, &rng.EndExcluding); err != nil {
+			return nil, err
+		}
+
+		if rng.Contains(version) && !seen[cve] {
+			seen[cve] = true
+			cves = append(cves, cve)
+		}
+	}
+
+	return cves, rows.Err()
+}
+
+// Canonical returns the canonical name of the product identified by the specified
+// CPE name, as stored in the `aliases` table, such as `a:nginx:nginx` for both
+// `cpe:/a:igor_sysoev:nginx` and `cpe:/a:nginx:nginx:1.9.4`. The name of the
+// product is returned as-is if it has no aliases, or empty if it is invalid.
+func Canonical(db *sql.DB, cpe string) (string, error) {
+	elems := strings.Split(strings.TrimPrefix(cpe, "cpe:/"), ":")
+
+	if len(elems) < 3 {
+		return "", nil
+	}
+
+	prod := strings.Join(elems[:3], ":")
+
+	var canonical string
+	err := db.QueryRow(`select canonical from aliases where cpe = ?`, strings.ToLower(prod)).Scan(&canonical)
+
+	switch err {
+	case nil:
+		return canonical, nil
+	case sql.ErrNoRows:
+		return prod, nil
+	}
+
+	return "", err
+}
+
+// Search returns the CVE numbers matching the specified full-text query, such
+// as `"remote code execution" AND apache`, ordered by relevance. The summaries
+// and affected product names are searched, which requires the database to be
+// built with the `--fts` argument.
+func Search(db *sql.DB, query string) ([]string, error) {
+	rows, err := db.Query(`select cve from vulns_fts where vulns_fts match ? order by rank`, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var cves []string
+
+	for rows.Next() {
+		var cve string
+
+		if err = rows.Scan(&cve); err != nil {
+			return nil, err
+		}
+
+		cves = append(cves, cve)
+	}
+
+	return cves, rows.Err()
+}
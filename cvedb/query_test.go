@@ -0,0 +1,95 @@
+package cvedb
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestAffected(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	stmts := []string {
+		`create table vulns (id int not null, cve text, status text, primary key(id))`,
+		`create table affected (vuln_id int not null, cpe text, version text, version_key text, canonical text)`,
+		`create table ranges (vuln_id int not null, cpe text, start_including text, start_excluding text, end_including text, end_excluding text, canonical text)`,
+		`create table aliases (cpe text not null, canonical text, primary key(cpe))`,
+		`insert into aliases values ('a:igor_sysoev:nginx', 'a:nginx:nginx'), ('a:nginx:nginx', 'a:nginx:nginx')`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err = db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vulns := []struct {
+		cve, status, cpe, version string
+		rng *Range
+	}{
+		{ "2015-0001", "", "a:nginx:nginx", "1.9.4", nil },
+		{ "2015-0002", "", "a:nginx:nginx", "", nil },
+		{ "2015-0003", "", "a:nginx:nginx", "", &Range { StartIncluding: "1.9.0", EndExcluding: "1.9.5" } },
+		{ "2015-0004", "rejected", "a:nginx:nginx", "1.9.4", nil },
+		{ "2015-0005", "", "a:igor_sysoev:nginx", "1.9.4", nil },
+		{ "2015-0006", "", "a:nginx:nginx", "1.9.40", nil },
+		{ "2015-0007", "", "a:nginx:nginx", "1.10.0RC1", nil },
+		{ "2015-0008", "", "a:apache:http_server", "1.9.4", nil },
+	}
+
+	for _, vuln := range vulns {
+		key, _ := Key(vuln.cve)
+		canonical, _ := Canonical(db, vuln.cpe)
+
+		if _, err = db.Exec(`insert into vulns values (?, ?, ?)`, key, vuln.cve, vuln.status); err != nil {
+			t.Fatal(err)
+		}
+
+		if vuln.rng != nil {
+			_, err = db.Exec(`insert into ranges values (?, ?, ?, ?, ?, ?, ?)`, key, "cpe:/" + vuln.cpe, vuln.rng.StartIncluding, vuln.rng.StartExcluding, vuln.rng.EndIncluding, vuln.rng.EndExcluding, canonical)
+		} else {
+			_, err = db.Exec(`insert into affected values (?, ?, ?, ?, ?)`, key, "cpe:/" + vuln.cpe + ":" + vuln.version, vuln.version, VersionKey(vuln.version), canonical)
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		cpe, version string
+		want string
+	}{
+		{ "cpe:/a:nginx:nginx", "1.9.4", "2015-0001,2015-0002,2015-0003,2015-0005" },
+		{ "cpe:/a:nginx:nginx", "1.9.4.0", "2015-0001,2015-0002,2015-0003,2015-0005" },
+		{ "cpe:/a:igor_sysoev:nginx", "1.9.4", "2015-0001,2015-0002,2015-0003,2015-0005" },
+		{ "cpe:/a:nginx:nginx", "1.9.40", "2015-0002,2015-0006" },
+		{ "cpe:/a:nginx:nginx", "1.10.0rc1", "2015-0002,2015-0007" },
+		{ "cpe:/a:nginx:nginx", "1.10.0", "2015-0002" },
+		{ "cpe:/a:apache:http_server", "1.9.4", "2015-0008" },
+		{ "cpe:/a:apache:tomcat", "1.9.4", "" },
+	}
+
+	for _, test := range tests {
+		cves, err := Affected(db, test.cpe, test.version)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sort.Strings(cves)
+
+		if got := strings.Join(cves, ","); got != test.want {
+			t.Errorf("Affected(%q, %q) = %q, want %q", test.cpe, test.version, got, test.want)
+		}
+	}
+}
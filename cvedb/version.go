@@ -0,0 +1,187 @@
+// Package cvedb implements helpers for querying the vulnerability database
+// generated by cve2hs.
+package cvedb
+
+import (
+	"strings"
+)
+
+// CompareVersions compares two version strings, returning -1, 0 or 1 if a is
+// respectively lower than, equal to or greater than b.
+//
+// The versions are split into numeric and alphabetic segments, which are
+// compared segment by segment. Segments such as `rc` or `beta` mark a
+// pre-release, thus `1.0rc1` is lower than `1.0`, while other letters mark a
+// patch release, thus `1.0.1a` is greater than `1.0.1`, but lower than `1.0.2`.
+// The segments are ranked as pre-release < end of the version < patch letters
+// < numbers, and compared numerically or lexically within the same rank. Zero
+// segments before an alphabetic segment or the end of the version are ignored,
+// thus `1.0` is equal to `1.0.0` and `1`, and `1.0a` to `1.0.0a`.
+func CompareVersions(a, b string) int {
+	as := normalizeVersion(a)
+	bs := normalizeVersion(b)
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		ar, br := rankEnd, rankEnd
+
+		if i < len(as) {
+			ar = segmentRank(as[i])
+		}
+
+		if i < len(bs) {
+			br = segmentRank(bs[i])
+		}
+
+		switch {
+		case ar < br:
+			return -1
+		case ar > br:
+			return 1
+		case ar == rankEnd:
+			return 0
+		}
+
+		if c := compareSegments(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// Ranks of the segments, with rankEnd standing for the end of the version.
+const (
+	rankPreRelease = iota + 1
+	rankEnd
+	rankPatch
+	rankNumber
+)
+
+var preReleases = map[string]bool {
+	"alpha": true, "beta": true, "rc": true, "cr": true,
+	"pre": true, "preview": true, "dev": true, "snapshot": true,
+}
+
+func segmentRank(seg string) int {
+	switch {
+	case isNumeric(seg):
+		return rankNumber
+	case preReleases[seg]:
+		return rankPreRelease
+	}
+
+	return rankPatch
+}
+
+// Compares two segments of the same rank, see normalizeVersion.
+func compareSegments(a, b string) int {
+	if isNumeric(a) && len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+// Splits the version into segments, with the alphabetic ones in lowercase and
+// the numeric ones without leading zeros. Runs of zero segments before an
+// alphabetic segment or the end of the version are dropped.
+func normalizeVersion(ver string) []string {
+	segs := splitVersion(strings.ToLower(ver))
+	norm := make([]string, 0, len(segs))
+
+	for i := 0; i < len(segs); i++ {
+		seg := segs[i]
+
+		if !isNumeric(seg) {
+			norm = append(norm, seg)
+			continue
+		}
+
+		seg = strings.TrimLeft(seg, "0")
+
+		if len(seg) == 0 {
+			j := i
+			for j < len(segs) && isNumeric(segs[j]) && strings.Trim(segs[j], "0") == "" {
+				j++
+			}
+
+			if j == len(segs) || !isNumeric(segs[j]) {
+				i = j - 1
+				continue
+			}
+
+			seg = "0"
+		}
+
+		norm = append(norm, seg)
+	}
+
+	return norm
+}
+
+// Splits the version into numeric and alphabetic segments, dropping separators.
+func splitVersion(ver string) []string {
+	var segs []string
+	var cur  []byte
+
+	for i := 0; i < len(ver); i++ {
+		c := ver[i]
+		alnum := c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+
+		if len(cur) > 0 && (!alnum || isDigit(c) != isDigit(cur[0])) {
+			segs = append(segs, string(cur))
+			cur = nil
+		}
+
+		if alnum {
+			cur = append(cur, c)
+		}
+	}
+
+	if len(cur) > 0 {
+		segs = append(segs, string(cur))
+	}
+
+	return segs
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Segments are either fully numeric or fully alphabetic, see splitVersion.
+func isNumeric(seg string) bool {
+	return len(seg) > 0 && isDigit(seg[0])
+}
+
+// VersionKey returns a key of the specified version, which sorts the same way
+// as the versions are ordered by CompareVersions when compared bytewise, in
+// order to be stored in the `version_key` column of the `affected` table.
+//
+// Each segment is prefixed by the digit of its rank, and the end of the version
+// is marked by the digit of its own, thus `2.0rc1` is lower than `2.0`, which is
+// equal to `2`. Numeric segments are also prefixed by their length, and the
+// alphabetic ones terminated by a `.`, which sorts before any letter.
+func VersionKey(ver string) string {
+	segs := normalizeVersion(ver)
+	key  := make([]byte, 0, len(ver) * 2)
+
+	for _, seg := range segs {
+		rank := segmentRank(seg)
+		key = append(key, byte('0' + rank))
+
+		if rank == rankNumber {
+			key = append(key, byte('0' + len(seg)))
+			key = append(key, seg...)
+		} else {
+			key = append(key, seg...)
+			key = append(key, '.')
+		}
+	}
+
+	return string(append(key, byte('0' + rankEnd)))
+}
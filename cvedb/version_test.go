@@ -0,0 +1,100 @@
+package cvedb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{ "1.0", "1.0", 0 },
+		{ "1.0", "1.0.0", 0 },
+		{ "1", "1.0.0", 0 },
+		{ "1.0", "1.1", -1 },
+		{ "1.10", "1.9", 1 },
+		{ "1.01", "1.1", 0 },
+		{ "1.0", "1.0a", -1 },
+		{ "1.0a", "1.0.0", 1 },
+		{ "1.0a", "1.0.0a", 0 },
+		{ "1.0a", "1.0b", -1 },
+		{ "1.0a", "1.0.1", -1 },
+		{ "1.0.1a", "1.0.1", 1 },
+		{ "1.0.1a", "1.0.2", -1 },
+		{ "1.0rc1", "1.0", -1 },
+		{ "1.0rc1", "1.0.0rc1", 0 },
+		{ "1.0rc1", "1.0rc2", -1 },
+		{ "1.0beta2", "1.0rc1", -1 },
+		{ "1.0.1beta1", "1.0.1a", -1 },
+		{ "1.0.1beta1", "1.0.1", -1 },
+		{ "1.0.1beta1", "1.0.0", 1 },
+		{ "1.0RC1", "1.0rc1", 0 },
+		{ "1.0_p1", "1.0.0", 1 },
+		{ "1.0_p1", "1.0.1", -1 },
+		{ "2.4.10", "2.4.9", 1 },
+	}
+
+	for _, test := range tests {
+		if got := CompareVersions(test.a, test.b); got != test.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+
+		if got := CompareVersions(test.b, test.a); got != -test.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", test.b, test.a, got, -test.want)
+		}
+	}
+}
+
+func TestRangeContains(t *testing.T) {
+	tests := []struct {
+		rng  Range
+		ver  string
+		want bool
+	}{
+		{ Range { EndExcluding: "1.0.1a" }, "1.0.1beta1", true },
+		{ Range { EndExcluding: "1.0.1a" }, "1.0.1", true },
+		{ Range { EndExcluding: "1.0.1a" }, "1.0.1a", false },
+		{ Range { StartIncluding: "2.4", EndExcluding: "2.4.10" }, "2.4.0", true },
+		{ Range { StartIncluding: "2.4", EndExcluding: "2.4.10" }, "2.4.10", false },
+		{ Range { StartExcluding: "1.0", EndIncluding: "1.5.3" }, "1.0.0", false },
+		{ Range { StartExcluding: "1.0", EndIncluding: "1.5.3" }, "1.5.3", true },
+	}
+
+	for _, test := range tests {
+		if got := test.rng.Contains(test.ver); got != test.want {
+			t.Errorf("%+v.Contains(%q) = %v, want %v", test.rng, test.ver, got, test.want)
+		}
+	}
+}
+
+func TestVersionKey(t *testing.T) {
+	versions := []string {
+		"", "0", "1", "1.0", "1.0.0", "1.0a", "1.0.0a", "1.0b", "1.0rc1", "1.0.0rc1",
+		"1.0RC2", "1.0beta", "1.0beta1", "1.0_p1", "1.0.1", "1.0.1a", "1.0.1beta1",
+		"1.1", "1.9", "1.10", "1.01", "2", "2.0-alpha", "2.0.0.0", "2.0a", "10.0",
+		"0.9", "0.10", "3.0dev", "3.0snapshot", "4.4.0-21", "2017.1", "1.2.3.4.5",
+	}
+
+	sign := func(c int) int {
+		switch {
+		case c < 0:
+			return -1
+		case c > 0:
+			return 1
+		}
+
+		return 0
+	}
+
+	for _, a := range versions {
+		for _, b := range versions {
+			want := CompareVersions(a, b)
+
+			if got := sign(strings.Compare(VersionKey(a), VersionKey(b))); got != want {
+				t.Errorf("VersionKey(%q) = %q, VersionKey(%q) = %q, compare = %d, want %d", a, VersionKey(a), b, VersionKey(b), got, want)
+			}
+		}
+	}
+}
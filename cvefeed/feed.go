@@ -0,0 +1,123 @@
+// Package cvefeed implements helpers for reading the vulnerability feeds
+// imported by cve2hs.
+package cvefeed
+
+import (
+	"io"
+	"os"
+	"errors"
+	"strings"
+	"path/filepath"
+	"encoding/json"
+)
+
+// ErrNotArray is returned by Each when the value is not an array.
+var ErrNotArray = errors.New("cvefeed: expected an array")
+
+// IsJSON checks whether the specified file name refers to a JSON document,
+// optionally gzip compressed.
+func IsJSON(file string) bool {
+	return strings.HasSuffix(file, ".json") || strings.HasSuffix(file, ".json.gz")
+}
+
+// IsXML checks whether the specified file name refers to an XML document,
+// optionally gzip compressed.
+func IsXML(file string) bool {
+	return strings.HasSuffix(file, ".xml") || strings.HasSuffix(file, ".xml.gz")
+}
+
+// Walk calls fn with the specified file, or with the XML and JSON documents
+// within the specified directory and its subdirectories. The documents found
+// within a directory are not guaranteed to be feeds, such as the `delta.json`
+// and `deltaLog.json` files of the cvelistV5 repository, so they should be
+// read with Decode, which skips the fields and documents it does not know.
+func Walk(root string, fn func(path string) error) error {
+	return filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if f.IsDir() || path != root && !IsJSON(f.Name()) && !IsXML(f.Name()) {
+			return nil
+		}
+
+		return fn(path)
+	})
+}
+
+// Decode reads the top-level object of a JSON document, and calls the function
+// registered for each of its fields, which has to consume the value of the field
+// from the decoder. Other fields are skipped, and so are documents which are not
+// an object, such as the change log of the cvelistV5 repository.
+func Decode(r io.Reader, fields map[string]func(dec *json.Decoder) error) error {
+	var err error
+	var tok json.Token
+
+	dec := json.NewDecoder(r)
+
+	if tok, err = dec.Token(); err != nil {
+		if err == io.EOF {
+			err = nil
+		}
+
+		return err
+	}
+
+	if tok != json.Delim('{') {
+		return nil
+	}
+
+	for dec.More() {
+		if tok, err = dec.Token(); err != nil {
+			return err
+		}
+
+		name, _ := tok.(string)
+
+		if fn, ok := fields[name]; ok {
+			err = fn(dec)
+		} else {
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+
+	return err
+}
+
+// Each reads the array at the current position of the decoder, and calls fn
+// for each of its elements, which has to consume the element from the decoder.
+// This allows the feeds to be read one entry at a time, so the whole array is
+// never held in memory. A null value is treated as an empty array.
+func Each(dec *json.Decoder, fn func() error) error {
+	var err error
+	var tok json.Token
+
+	if tok, err = dec.Token(); err != nil {
+		return err
+	}
+
+	if tok == nil {
+		return nil
+	}
+
+	if tok != json.Delim('[') {
+		return ErrNotArray
+	}
+
+	for dec.More() {
+		if err = fn(); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+
+	return err
+}
@@ -0,0 +1,212 @@
+package cvefeed
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"path/filepath"
+	"encoding/json"
+)
+
+func TestIsJSON(t *testing.T) {
+	tests := []struct {
+		file string
+		json, xml bool
+	}{
+		{ "nvdcve-2.0-2014.json.gz", true, false },
+		{ "CVE-2014-0160.json", true, false },
+		{ "nvdcve-2.0-2014.xml", false, true },
+		{ "nvdcve-2.0-2014.xml.gz", false, true },
+		{ "README.md", false, false },
+		{ "cves.json.bz2", false, false },
+	}
+
+	for _, test := range tests {
+		if got := IsJSON(test.file); got != test.json {
+			t.Errorf("IsJSON(%q) = %v, want %v", test.file, got, test.json)
+		}
+
+		if got := IsXML(test.file); got != test.xml {
+			t.Errorf("IsXML(%q) = %v, want %v", test.file, got, test.xml)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		doc string
+		want []string
+	}{
+		{ `{"dataType":"CVE_RECORD","cveMetadata":{"cveId":"CVE-2014-0160"},"containers":{}}`, []string{ "CVE-2014-0160" } },
+		{ `{"cveMetadata":{"cveId":"CVE-2014-0160"},"cveMetadata":{"cveId":"CVE-2014-0161"}}`, []string{ "CVE-2014-0160", "CVE-2014-0161" } },
+		{ `{"fetchTime":"2024-01-01T00:00:00.000Z","new":[{"cveId":"CVE-2014-0160"}],"updated":[]}`, nil },
+		{ `[{"fetchTime":"2024-01-01T00:00:00.000Z","new":[{"cveId":"CVE-2014-0160"}]}]`, nil },
+		{ `"CVE-2014-0160"`, nil },
+		{ ``, nil },
+	}
+
+	for _, test := range tests {
+		got := decodeIDs(t, strings.NewReader(test.doc))
+
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("Decode(%q) = %v, want %v", test.doc, got, test.want)
+		}
+	}
+
+	for _, doc := range []string{ `{"cveMetadata":`, `{"cveMetadata":{"cveId":1}}`, `{"new":[}` } {
+		if err := Decode(strings.NewReader(doc), idFields(new([]string))); err == nil {
+			t.Errorf("Decode(%q) succeeded on invalid document", doc)
+		}
+	}
+}
+
+func TestEach(t *testing.T) {
+	tests := []struct {
+		doc string
+		want string
+		fails bool
+	}{
+		{ `{"CVE_Items":[{"id":"CVE-2014-0160"},{"id":"CVE-2014-0161"}],"CVE_data_timestamp":"2024-01-01T00:00Z"}`, "CVE-2014-0160,CVE-2014-0161", false },
+		{ `{"vulnerabilities":[]}`, "", false },
+		{ `{"vulnerabilities":null}`, "", false },
+		{ `{"vulnerabilities":{"id":"CVE-2014-0160"}}`, "", true },
+		{ `{"vulnerabilities":[{"id":"CVE-2014-0160"},{"id":1}]}`, "CVE-2014-0160", true },
+	}
+
+	for _, test := range tests {
+		var ids []string
+
+		each := func(dec *json.Decoder) error {
+			return Each(dec, func() error {
+				var item struct {
+					ID string `json:"id"`
+				}
+
+				if err := dec.Decode(&item); err != nil {
+					return err
+				}
+
+				ids = append(ids, item.ID)
+				return nil
+			})
+		}
+
+		err := Decode(strings.NewReader(test.doc), map[string]func(dec *json.Decoder) error {
+			"CVE_Items":       each,
+			"vulnerabilities": each,
+		})
+
+		if got := strings.Join(ids, ","); got != test.want {
+			t.Errorf("Each(%q) = %q, want %q", test.doc, got, test.want)
+		}
+
+		if (err != nil) != test.fails {
+			t.Errorf("Each(%q) returned %v, want failure %v", test.doc, err, test.fails)
+		}
+	}
+
+	dec := json.NewDecoder(strings.NewReader(`{"id":"CVE-2014-0160"}`))
+
+	if err := Each(dec, func() error { return nil }); err != ErrNotArray {
+		t.Errorf("Each on an object returned %v, want %v", err, ErrNotArray)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string {
+		"cves/delta.json":                     `{"fetchTime":"2024-01-01T00:00:00.000Z","numberOfChanges":1,"new":[{"cveId":"CVE-2014-0160"}],"updated":[],"error":[]}`,
+		"cves/deltaLog.json":                  `[{"fetchTime":"2024-01-01T00:00:00.000Z","numberOfChanges":1,"new":[{"cveId":"CVE-2014-0160"}],"updated":[],"error":[]}]`,
+		"cves/2014/0xxx/CVE-2014-0160.json":   `{"dataType":"CVE_RECORD","cveMetadata":{"cveId":"CVE-2014-0160","state":"PUBLISHED"},"containers":{"cna":{}}}`,
+		"cves/2021/44xxx/CVE-2021-44228.json": `{"dataType":"CVE_RECORD","cveMetadata":{"cveId":"CVE-2021-44228","state":"PUBLISHED"},"containers":{"cna":{}}}`,
+		"README.md":                           `# CVE List V5`,
+	}
+
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var paths []string
+	var ids   []string
+
+	err := Walk(dir, func(path string) error {
+		fp, err := os.Open(path)
+
+		if err != nil {
+			return err
+		}
+
+		defer fp.Close()
+
+		rel, _ := filepath.Rel(dir, path)
+		paths = append(paths, filepath.ToSlash(rel))
+		ids = append(ids, decodeIDs(t, fp)...)
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := strings.Join(paths, ","), "cves/2014/0xxx/CVE-2014-0160.json,cves/2021/44xxx/CVE-2021-44228.json,cves/delta.json,cves/deltaLog.json"; got != want {
+		t.Errorf("Walk(%q) visited %q, want %q", dir, got, want)
+	}
+
+	if got, want := strings.Join(ids, ","), "CVE-2014-0160,CVE-2021-44228"; got != want {
+		t.Errorf("Walk(%q) decoded %q, want %q", dir, got, want)
+	}
+
+	// files specified directly are passed on regardless of their extension
+
+	paths = nil
+	root := filepath.Join(dir, "README.md")
+
+	Walk(root, func(path string) error {
+		paths = append(paths, path)
+		return nil
+	})
+
+	if len(paths) != 1 || paths[0] != root {
+		t.Errorf("Walk(%q) visited %q, want the file itself", root, paths)
+	}
+}
+
+// Returns the fields collecting the IDs within `cveMetadata` into the specified slice.
+func idFields(ids *[]string) map[string]func(dec *json.Decoder) error {
+	return map[string]func(dec *json.Decoder) error {
+		"cveMetadata": func(dec *json.Decoder) error {
+			var meta struct {
+				ID string `json:"cveId"`
+			}
+
+			if err := dec.Decode(&meta); err != nil {
+				return err
+			}
+
+			*ids = append(*ids, meta.ID)
+			return nil
+		},
+	}
+}
+
+// Decodes the IDs of the specified document, failing the test on error.
+func decodeIDs(t *testing.T, r io.Reader) []string {
+	var ids []string
+
+	if err := Decode(r, idFields(&ids)); err != nil {
+		t.Fatal(err)
+	}
+
+	return ids
+}
@@ -0,0 +1,289 @@
+package cvefeed
+
+import (
+	"sort"
+	"regexp"
+	"strings"
+)
+
+// Product of the CPE dictionary, such as `a:apache:http_server`, along with the
+// tokens of its vendor and product names.
+type Product struct {
+	CPE string
+	Vendor, Product []string
+}
+
+// Dictionary indexes the products of the CPE dictionary by the tokens of their
+// product names, in order to find the products mentioned in the summaries.
+type Dictionary map[string][]*Product
+
+// Range is a version range of a product inferred from the summary of an entry,
+// such as "nginx before 1.9.5". The start of the inferred ranges is always
+// inclusive, StartExcluding only mirrors the columns of the configuration ranges.
+type Range struct {
+	CPE string
+	StartIncluding, StartExcluding, EndIncluding, EndExcluding string
+	Confidence float64
+	Phrase string
+}
+
+// Add indexes the product of the specified CPE name, such as
+// `a:apache:http_server`.
+func (dict Dictionary) Add(cpe string) {
+	elems := strings.Split(cpe, ":")
+
+	if len(elems) < 3 {
+		return
+	}
+
+	prod := &Product {
+		CPE:     strings.Join(elems[:3], ":"),
+		Vendor:  nameTokens(elems[1]),
+		Product: nameTokens(elems[2]),
+	}
+
+	for _, token := range prod.Product {
+		dict[token] = append(dict[token], prod)
+	}
+}
+
+var tokenRegex = regexp.MustCompile(`[a-z][a-z0-9]+`)
+
+// Returns the distinct lowercase words of the specified name or text, such as
+// `http` and `server` for `http_server`.
+func nameTokens(name string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+
+	for _, token := range tokenRegex.FindAllString(strings.ToLower(name), -1) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+const versionPattern = `(\d+(?:\.[0-9a-z]+)*)`
+
+// Phrases of the summaries specifying a version range, such as "2.4.x before
+// 2.4.10", "1.2 through 1.5.3" or "1.5.3 and earlier". The submatches are the
+// bounds of the range, with the start being optional.
+var rangePhrases = []struct {
+	Regex *regexp.Regexp
+	Inclusive bool
+}{
+	{ regexp.MustCompile(`(?i)\b(?:(?:versions? |from )?` + versionPattern + ` )?(?:through|thru) (?:versions? )?` + versionPattern), true },
+	{ regexp.MustCompile(`(?i)\b(?:between (?:versions? )?` + versionPattern + ` and )(?:versions? )?` + versionPattern), true },
+	{ regexp.MustCompile(`(?i)\b(?:(?:versions? )?` + versionPattern + `,? (?:and )?)?(?:before|prior to|earlier than|older than|lower than) (?:versions? )?` + versionPattern), false },
+	{ regexp.MustCompile(`(?i)\b()(?:versions? )?` + versionPattern + `,? (?:and|or) (?:earlier|prior|older|lower|below)\b`), true },
+	{ regexp.MustCompile(`(?i)\b()up to (?:and including )?(?:versions? )?` + versionPattern), true },
+}
+
+// Words joining the phrases of the same product, such as "2.2.x before 2.2.28
+// and 2.4.x before 2.4.10".
+var connectorWords = map[string]bool {
+	"and": true, "or": true, "as": true, "well": true, "also": true,
+	"version": true, "versions": true,
+}
+
+// maxWindow is the number of words preceding a phrase searched for the name of
+// the product.
+const maxWindow = 6
+
+// InferRanges extracts the version ranges specified in the summary of an entry,
+// and maps the product names preceding them to the CPE names of the dictionary.
+// The listed map holds the lowercase `part:vendor:product` names of the products
+// listed among the affected software of the entry. The confidence of each range
+// starts at 0.5, and is raised when the vendor name is also mentioned, or the
+// product is listed, and lowered when the product name was carried over from the
+// previous phrase. Ambiguous product names are skipped.
+func (dict Dictionary) InferRanges(summary string, listed map[string]bool) []Range {
+	var ranges []Range
+
+	// find the phrases, skipping the ones overlapping an earlier pattern,
+	// such as "before 2.4.10" within "2.4.x before 2.4.10"
+
+	var spans [][]int
+
+	for i, phrase := range rangePhrases {
+		for _, span := range phrase.Regex.FindAllStringSubmatchIndex(summary, -1) {
+			overlaps := false
+
+			for _, prev := range spans {
+				if span[0] < prev[1] && prev[0] < span[1] {
+					overlaps = true
+					break
+				}
+			}
+
+			if !overlaps {
+				spans = append(spans, append(span, i))
+			}
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	var last *Product
+	conf, prev := 0, 0
+
+	for _, span := range spans {
+		var start, end string
+
+		if span[2] >= 0 {
+			start = summary[span[2]:span[3]]
+		}
+
+		end = summary[span[4]:span[5]]
+
+		// the words preceding the phrase within the same sentence
+
+		text := summary[prev:span[0]]
+
+		if i := strings.LastIndexAny(text, ";:"); i >= 0 {
+			text = text[i + 1:]
+		}
+
+		if i := strings.LastIndex(text, ". "); i >= 0 {
+			text = text[i + 2:]
+		}
+
+		words := tokenRegex.FindAllString(strings.ToLower(text), -1)
+
+		if len(words) > maxWindow {
+			words = words[len(words) - maxWindow:]
+		}
+
+		prev = span[1]
+
+		prod, vendor := dict.matchProduct(words, listed)
+
+		if prod != nil {
+			last = prod
+			conf = 5
+
+			if vendor {
+				conf += 2
+			}
+
+			if listed[prod.CPE] {
+				conf += 2
+			}
+		} else if last != nil && onlyConnectors(words) {
+			prod = last
+			conf--
+		} else {
+			last = nil
+			continue
+		}
+
+		rng := Range {
+			CPE:        "cpe:/" + prod.CPE,
+			Confidence: float64(conf) / 10,
+			Phrase:     strings.TrimSpace(summary[span[0]:span[1]]),
+		}
+
+		// the start is only used for a series, such as 2.4.x, or when the end
+		// is within the same series, such as 2.4 before 2.4.10, or 1.0.1 before
+		// 1.0.1g, where the series is followed by a patch letter
+
+		if strings.HasSuffix(strings.ToLower(start), ".x") {
+			start = start[:len(start) - 2]
+		} else if !rangePhrases[span[6]].Inclusive && !sameSeries(start, end) {
+			start = ""
+		}
+
+		rng.StartIncluding = start
+
+		if rangePhrases[span[6]].Inclusive {
+			rng.EndIncluding = end
+		} else {
+			rng.EndExcluding = end
+		}
+
+		ranges = append(ranges, rng)
+	}
+
+	return ranges
+}
+
+// Returns the dictionary product whose name is fully mentioned in the specified
+// words, and whether its vendor name is also mentioned. The most specific one is
+// preferred, then the one listed in the entry, and nil is returned when multiple
+// products match equally.
+func (dict Dictionary) matchProduct(words []string, listed map[string]bool) (*Product, bool) {
+	var best *Product
+
+	mentioned := make(map[string]bool)
+
+	for _, word := range words {
+		mentioned[word] = true
+	}
+
+	contains := func(tokens []string) bool {
+		for _, token := range tokens {
+			if !mentioned[token] {
+				return false
+			}
+		}
+
+		return len(tokens) != 0
+	}
+
+	top, ties := 0, 0
+	seen := make(map[*Product]bool)
+
+	for _, word := range words {
+		for _, prod := range dict[word] {
+			if seen[prod] || !contains(prod.Product) {
+				continue
+			}
+
+			seen[prod] = true
+			score := len(prod.Product) * 4
+
+			if contains(prod.Vendor) {
+				score += 2
+			}
+
+			if listed[prod.CPE] {
+				score++
+			}
+
+			switch {
+			case score > top:
+				best, top, ties = prod, score, 0
+			case score == top:
+				ties++
+			}
+		}
+	}
+
+	if best == nil || ties != 0 {
+		return nil, false
+	}
+
+	return best, contains(best.Vendor)
+}
+
+var numericRegex = regexp.MustCompile(`^\d+(?:\.\d+)*`)
+
+// Checks whether the specified end of a range is within the series of the
+// specified start, by comparing the numeric prefix of the end, such as 1.0.1
+// for 1.0.1g, to the start.
+func sameSeries(start, end string) bool {
+	return strings.HasPrefix(end, start + ".") || numericRegex.FindString(end) == start
+}
+
+// Checks whether the specified words only join two phrases, see connectorWords.
+func onlyConnectors(words []string) bool {
+	for _, word := range words {
+		if !connectorWords[word] {
+			return false
+		}
+	}
+
+	return true
+}
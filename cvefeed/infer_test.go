@@ -0,0 +1,119 @@
+package cvefeed
+
+import (
+	"testing"
+)
+
+func TestInferRanges(t *testing.T) {
+	dict := make(Dictionary)
+
+	for _, cpe := range []string{ "a:openssl:openssl", "a:apache:http_server", "a:apache:tomcat", "a:nginx:nginx", "o:linux:linux_kernel", "a:foo:server", "a:bar:server" } {
+		dict.Add(cpe)
+	}
+
+	tests := []struct {
+		summary string
+		listed []string
+		want []Range
+	}{
+		{
+			"The TLS implementation in OpenSSL 1.0.1 before 1.0.1g does not properly handle Heartbeat Extension packets.", nil,
+			[]Range{ { CPE: "cpe:/a:openssl:openssl", StartIncluding: "1.0.1", EndExcluding: "1.0.1g", Confidence: 0.7, Phrase: "1.0.1 before 1.0.1g" } },
+		},
+		{
+			"OpenSSL before 0.9.8za, 1.0.0 before 1.0.0m, and 1.0.1 before 1.0.1h does not properly restrict processing of ChangeCipherSpec messages.", nil,
+			[]Range{
+				{ CPE: "cpe:/a:openssl:openssl", EndExcluding: "0.9.8za", Confidence: 0.7, Phrase: "before 0.9.8za" },
+				{ CPE: "cpe:/a:openssl:openssl", StartIncluding: "1.0.0", EndExcluding: "1.0.0m", Confidence: 0.6, Phrase: "1.0.0 before 1.0.0m" },
+				{ CPE: "cpe:/a:openssl:openssl", StartIncluding: "1.0.1", EndExcluding: "1.0.1h", Confidence: 0.5, Phrase: "1.0.1 before 1.0.1h" },
+			},
+		},
+		{
+			"The mod_proxy module in the Apache HTTP Server 2.2.x before 2.2.28 and 2.4.x before 2.4.10 allows remote attackers to cause a denial of service.", []string{ "a:apache:http_server" },
+			[]Range{
+				{ CPE: "cpe:/a:apache:http_server", StartIncluding: "2.2", EndExcluding: "2.2.28", Confidence: 0.9, Phrase: "2.2.x before 2.2.28" },
+				{ CPE: "cpe:/a:apache:http_server", StartIncluding: "2.4", EndExcluding: "2.4.10", Confidence: 0.8, Phrase: "2.4.x before 2.4.10" },
+			},
+		},
+		{
+			"nginx 1.9 before 1.9.5 allows remote attackers to cause a denial of service.", nil,
+			[]Range{ { CPE: "cpe:/a:nginx:nginx", StartIncluding: "1.9", EndExcluding: "1.9.5", Confidence: 0.7, Phrase: "1.9 before 1.9.5" } },
+		},
+		{
+			"nginx 1.8 before 1.9.5 allows remote attackers to cause a denial of service.", nil,
+			[]Range{ { CPE: "cpe:/a:nginx:nginx", EndExcluding: "1.9.5", Confidence: 0.7, Phrase: "1.8 before 1.9.5" } },
+		},
+		{
+			"nginx 1.0.1 before 1.0.10 allows remote attackers to cause a denial of service.", nil,
+			[]Range{ { CPE: "cpe:/a:nginx:nginx", EndExcluding: "1.0.10", Confidence: 0.7, Phrase: "1.0.1 before 1.0.10" } },
+		},
+		{
+			"The Linux kernel through 4.5.2 allows local users to obtain sensitive information.", nil,
+			[]Range{ { CPE: "cpe:/o:linux:linux_kernel", EndIncluding: "4.5.2", Confidence: 0.7, Phrase: "through 4.5.2" } },
+		},
+		{
+			"Apache Tomcat versions 7.0.0 through 7.0.70 are affected.", nil,
+			[]Range{ { CPE: "cpe:/a:apache:tomcat", StartIncluding: "7.0.0", EndIncluding: "7.0.70", Confidence: 0.7, Phrase: "versions 7.0.0 through 7.0.70" } },
+		},
+		{
+			"nginx 1.6.1 and earlier allows remote attackers to inject commands.", nil,
+			[]Range{ { CPE: "cpe:/a:nginx:nginx", EndIncluding: "1.6.1", Confidence: 0.7, Phrase: "1.6.1 and earlier" } },
+		},
+		{
+			"Multiple vulnerabilities in server prior to version 3.1 allow remote code execution.", nil,
+			nil,
+		},
+		{
+			"Multiple vulnerabilities in server prior to version 3.1 allow remote code execution.", []string{ "a:foo:server" },
+			[]Range{ { CPE: "cpe:/a:foo:server", EndExcluding: "3.1", Confidence: 0.7, Phrase: "prior to version 3.1" } },
+		},
+		{
+			"A buffer overflow in the parser before 2.0 allows remote attackers to execute arbitrary code.", nil,
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		listed := make(map[string]bool)
+
+		for _, cpe := range test.listed {
+			listed[cpe] = true
+		}
+
+		got := dict.InferRanges(test.summary, listed)
+
+		if len(got) != len(test.want) {
+			t.Errorf("InferRanges(%q) = %+v, want %+v", test.summary, got, test.want)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("InferRanges(%q)[%d] = %+v, want %+v", test.summary, i, got[i], test.want[i])
+			}
+		}
+	}
+}
+
+func TestSameSeries(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want bool
+	}{
+		{ "2.4", "2.4.10", true },
+		{ "1.0.1", "1.0.1g", true },
+		{ "0.9.8", "0.9.8za", true },
+		{ "1.0", "1.0.1g", true },
+		{ "1.0.1", "1.0.10", false },
+		{ "1.0.1", "1.0.2", false },
+		{ "1.8", "1.9.5", false },
+		{ "2.4", "2.40", false },
+		{ "", "1.9.5", false },
+	}
+
+	for _, test := range tests {
+		if got := sameSeries(test.start, test.end); got != test.want {
+			t.Errorf("sameSeries(%q, %q) = %v, want %v", test.start, test.end, got, test.want)
+		}
+	}
+}
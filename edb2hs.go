@@ -0,0 +1,217 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+	"bufio"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"database/sql"
+	"encoding/csv"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/RoliSoft/Host-Scanner-Scripts/cvedb"
+	"github.com/RoliSoft/Host-Scanner-Scripts/hsformat"
+)
+
+var entries []item
+
+type item struct {
+	ID int
+	Title string
+	Type string
+	Platform string
+	Date string
+	Verified bool
+	CVEs []string
+}
+
+var cveRegex = regexp.MustCompile(`(?i)\bCVE-(\d{4}-\d{4,})\b`)
+
+// Reads the specified `files_exploits.csv` file of Exploit-DB, optionally
+// compressed, and extracts the CVE numbers of the exploits from the `codes`
+// column, or from the description when the file has no such column.
+func parseInput(file string) error {
+	var err error
+	var fp  io.ReadCloser
+	var row []string
+
+	if fp, err = hsformat.Open(file); err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	cr := csv.NewReader(bufio.NewReader(fp))
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	if row, err = cr.Read(); err != nil {
+		return err
+	}
+
+	cols := make(map[string]int)
+
+	for i, name := range row {
+		cols[strings.TrimSpace(name)] = i
+	}
+
+	if _, ok := cols["id"]; !ok {
+		return errors.New("no id column in exploit list")
+	}
+
+	col := func(row []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := cols[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+		}
+
+		return ""
+	}
+
+	for {
+		if row, err = cr.Read(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+
+			break
+		}
+
+		id, _ := strconv.Atoi(col(row, "id"))
+
+		if id == 0 {
+			continue
+		}
+
+		ent := item {
+			ID:       id,
+			Title:    col(row, "description"),
+			Type:     col(row, "type"),
+			Platform: col(row, "platform"),
+			Date:     col(row, "date_published", "date"),
+			Verified: col(row, "verified") == "1",
+		}
+
+		codes := col(row, "codes")
+
+		if _, ok := cols["codes"]; !ok {
+			codes = ent.Title
+		}
+
+		seen := make(map[string]bool)
+
+		for _, match := range cveRegex.FindAllStringSubmatch(codes, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				ent.CVEs = append(ent.CVEs, "CVE-" + match[1])
+			}
+		}
+
+		if len(ent.CVEs) != 0 {
+			entries = append(entries, ent)
+		}
+	}
+
+	return err
+}
+
+// Writes the globally loaded entries to the `exploits` table of the specified
+// CVE database, replacing the ones imported previously from Exploit-DB.
+func serializeEntries(file string, debug bool) error {
+	var err error
+
+	if debug {
+		return hsformat.WriteJSON(file, entries)
+	}
+
+	var db *sql.DB
+	var tx *sql.Tx
+
+	stmts := []string {
+		`create table if not exists exploits (vuln_id int not null, source text, edb_id int, title text, type text, platform text, date int, verified boolean)`,
+		`create index if not exists exploit_vuln_idx on exploits (vuln_id)`,
+	}
+
+	if db, err = cvedb.OpenDatabase(file, stmts...); err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	if tx, err = db.Begin(); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(`delete from exploits where source = 'exploit-db'`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var stm *sql.Stmt
+
+	if stm, err = tx.Prepare(`insert into exploits values (?, 'exploit-db', ?, ?, ?, ?, ?, ?)`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, entry := range entries {
+		for _, cve := range entry.CVEs {
+			var id int64
+
+			if id, err = cvedb.Key(cve); err != nil {
+				println("Skipping " + cve + ": " + err.Error())
+				continue
+			}
+
+			if _, err = stm.Exec(id, entry.ID, entry.Title, entry.Type, entry.Platform, cvedb.UnixDate(entry.Date), entry.Verified); err != nil {
+				stm.Close()
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	stm.Close()
+
+	if _, err = tx.Exec(`insert or replace into meta values ('edb_imported', ?)`, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Entry point of the application.
+func main() {
+	if len(os.Args) < 3 {
+		println("usage: edb2hs [--json] input output")
+		os.Exit(-1)
+	}
+
+	var err error
+	var dbg bool
+
+	if os.Args[1] == "--json" {
+		dbg = true
+		os.Args = os.Args[1:]
+	}
+
+	println("Parsing Exploit-DB list...")
+
+	if err = parseInput(os.Args[1]); err != nil {
+		println(err.Error())
+		os.Exit(-1)
+	}
+
+	println("Writing parsed data...")
+
+	if err = serializeEntries(os.Args[2], dbg); err != nil {
+		println(err.Error())
+		os.Exit(-1)
+	}
+}
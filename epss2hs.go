@@ -0,0 +1,281 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sort"
+	"time"
+	"bufio"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"database/sql"
+	"path/filepath"
+	"encoding/csv"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/RoliSoft/Host-Scanner-Scripts/cvedb"
+	"github.com/RoliSoft/Host-Scanner-Scripts/hsformat"
+)
+
+var entries []*scores
+
+// Scores of a daily EPSS file, such as epss_scores-2024-01-02.csv.gz.
+type scores struct {
+	Model string
+	Date time.Time
+	Items []item
+}
+
+type item struct {
+	CVE string
+	EPSS, Percentile float64
+}
+
+var dateRegex = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+// Reads the specified EPSS file, optionally compressed. The model version and
+// the score date are read from the leading comment, such as
+// `#model_version:v2023.03.01,score_date:2024-01-02T00:00:00+0000`, or the
+// date is taken from the name of the file if it has no such comment.
+func parseInput(file string) error {
+	var err error
+	var fp  io.ReadCloser
+	var row []string
+
+	if fp, err = hsformat.Open(file); err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	ent := &scores { }
+	br  := bufio.NewReader(fp)
+
+	if sig, _ := br.Peek(1); len(sig) == 1 && sig[0] == '#' {
+		var ln string
+
+		if ln, err = br.ReadString('\n'); err != nil {
+			return err
+		}
+
+		for _, field := range strings.Split(strings.TrimSpace(ln[1:]), ",") {
+			kv := strings.SplitN(field, ":", 2)
+
+			if len(kv) != 2 {
+				continue
+			}
+
+			switch kv[0] {
+			case "model_version":
+				ent.Model = kv[1]
+			case "score_date":
+				ent.Date, _ = time.Parse("2006-01-02", dateRegex.FindString(kv[1]))
+			}
+		}
+	}
+
+	if ent.Date.IsZero() {
+		ent.Date, _ = time.Parse("2006-01-02", dateRegex.FindString(filepath.Base(file)))
+	}
+
+	if ent.Date.IsZero() {
+		return errors.New("no score date in " + file)
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+
+	if row, err = cr.Read(); err != nil {
+		return err
+	}
+
+	cols := make(map[string]int)
+
+	for i, name := range row {
+		cols[strings.TrimSpace(name)] = i
+	}
+
+	if _, ok := cols["cve"]; !ok {
+		return errors.New("no cve column in " + file)
+	}
+
+	col := func(row []string, name string) float64 {
+		if i, ok := cols[name]; ok && i < len(row) {
+			val, _ := strconv.ParseFloat(strings.TrimSpace(row[i]), 64)
+			return val
+		}
+
+		return 0
+	}
+
+	for {
+		if row, err = cr.Read(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+
+			break
+		}
+
+		if cols["cve"] >= len(row) {
+			continue
+		}
+
+		ent.Items = append(ent.Items, item {
+			CVE:        strings.TrimSpace(row[cols["cve"]]),
+			EPSS:       col(row, "epss"),
+			Percentile: col(row, "percentile"),
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	entries = append(entries, ent)
+
+	return nil
+}
+
+// Writes the globally loaded entries to the `epss` table of the specified CVE
+// database. The scores of a date imported previously are replaced, and only the
+// scores of the latest `keep` dates are kept.
+func serializeEntries(file string, keep int, debug bool) error {
+	var err error
+
+	if debug {
+		return hsformat.WriteJSON(file, entries)
+	}
+
+	var db *sql.DB
+	var tx *sql.Tx
+
+	stmts := []string {
+		`create table if not exists epss (vuln_id int not null, cve text, epss real, percentile real, score_date int not null, primary key(vuln_id, score_date))`,
+		`create index if not exists epss_date_idx on epss (score_date)`,
+	}
+
+	if db, err = cvedb.OpenDatabase(file, stmts...); err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	var prev int64
+	db.QueryRow(`select coalesce(max(score_date), 0) from epss`).Scan(&prev)
+
+	if tx, err = db.Begin(); err != nil {
+		return err
+	}
+
+	var stm *sql.Stmt
+
+	if stm, err = tx.Prepare(`insert or replace into epss values (?, ?, ?, ?, ?)`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+
+	for _, ent := range entries {
+		date := ent.Date.Unix()
+
+		if _, err = tx.Exec(`delete from epss where score_date = ?`, date); err != nil {
+			stm.Close()
+			tx.Rollback()
+			return err
+		}
+
+		for _, entry := range ent.Items {
+			var id int64
+
+			if id, err = cvedb.Key(entry.CVE); err != nil {
+				println("Skipping " + entry.CVE + ": " + err.Error())
+				continue
+			}
+
+			if _, err = stm.Exec(id, strings.TrimPrefix(entry.CVE, "CVE-"), entry.EPSS, entry.Percentile, date); err != nil {
+				stm.Close()
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	stm.Close()
+
+	// drop the scores older than the latest `keep` dates
+
+	if _, err = tx.Exec(`delete from epss where score_date not in (select distinct score_date from epss order by score_date desc limit ?)`, keep); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// the model and date of the scores are only updated when importing newer
+	// scores than the ones in the database, not when adding older history
+
+	meta := map[string]string {
+		"epss_imported": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if latest := entries[len(entries) - 1]; latest.Date.Unix() >= prev {
+		meta["epss_model"] = latest.Model
+		meta["epss_score_date"] = latest.Date.Format("2006-01-02")
+	}
+
+	for key, value := range meta {
+		if _, err = tx.Exec(`insert or replace into meta values (?, ?)`, key, value); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Entry point of the application.
+func main() {
+	var err error
+	var dbg bool
+
+	keep := 30
+
+	for len(os.Args) > 2 && strings.HasPrefix(os.Args[1], "--") {
+		switch os.Args[1] {
+		case "--json":
+			dbg = true
+		case "--keep":
+			if keep, err = strconv.Atoi(os.Args[2]); err != nil || keep < 1 {
+				println("invalid number of dates to keep: " + os.Args[2])
+				os.Exit(-1)
+			}
+
+			os.Args = os.Args[1:]
+		}
+
+		os.Args = os.Args[1:]
+	}
+
+	if len(os.Args) < 3 {
+		println("usage: epss2hs [--json] [--keep dates] input... output")
+		os.Exit(-1)
+	}
+
+	println("Parsing EPSS scores...")
+
+	for _, file := range os.Args[1:len(os.Args) - 1] {
+		if err = parseInput(file); err != nil {
+			println(err.Error())
+			os.Exit(-1)
+		}
+	}
+
+	println("Writing parsed data...")
+
+	if err = serializeEntries(os.Args[len(os.Args) - 1], keep, dbg); err != nil {
+		println(err.Error())
+		os.Exit(-1)
+	}
+}
@@ -54,22 +54,71 @@ if [[ -z $1 || $1 == "zudp" ]]; then
 	)
 fi
 
+if [[ -z $1 || $1 == "cwe" ]]; then
+	echo -e "\e[32mDownloading CWE catalog...\e[39m"
+
+	rm -f cwe-catalog.xml cwe-catalog.xml.zip
+	wget https://cwe.mitre.org/data/xml/cwec_latest.xml.zip -O cwe-catalog.xml.zip
+	unzip -p cwe-catalog.xml.zip > cwe-catalog.xml
+	rm -f cwe-catalog.xml.zip
+fi
+
 if [[ -z $1 || $1 == "cve" ]]; then
-	rm -f cve-items.xml
 	year=$(date +'%Y')
 	for i in $(seq 2002 ${year}); do
 		echo -e "\e[32mDownloading CVE database for $i...\e[39m"
-		rm -f "cve-items-$i.xml"
-		wget "http://static.nvd.nist.gov/feeds/xml/cve/nvdcve-2.0-$i.xml.gz" -O "cve-items-$i.xml.gz"
-
-		echo -e "\e[32mProcessing CVE database for $i...\e[39m"
-		gzip -d "cve-items-$i.xml.gz"
-		sed -i -r -e 's#<(/)?(vuln|cvss|cpe-lang):#<\1#g' "cve-items-$i.xml"
-		cat "cve-items-$i.xml" >> cve-items.xml
-		rm -f "cve-items-$i.xml"
+		rm -f "nvdcve-2.0-$i.json.gz"
+		wget "https://nvd.nist.gov/feeds/json/cve/2.0/nvdcve-2.0-$i.json.gz" -O "nvdcve-2.0-$i.json.gz"
+	done
+fi
+
+if [[ -z $1 || $1 == "kev" ]]; then
+	echo -e "\e[32mDownloading KEV catalog...\e[39m"
+
+	rm -f known-exploited.json
+	wget https://www.cisa.gov/sites/default/files/feeds/known_exploited_vulnerabilities.json -O known-exploited.json
+fi
+
+if [[ -z $1 || $1 == "epss" ]]; then
+	echo -e "\e[32mDownloading EPSS scores...\e[39m"
+
+	# the daily files are kept, so the history is imported again when the
+	# database is rebuilt, and only the missing dates are downloaded
+
+	mkdir -p epss
+	keep=30
+
+	for i in $(seq 0 $((keep - 1))); do
+		day=$(date -u -d "-$i day" +'%Y-%m-%d')
+		[[ -f "epss/epss_scores-$day.csv.gz" ]] && continue
+		wget "https://epss.cyentia.com/epss_scores-$day.csv.gz" -O "epss/epss_scores-$day.csv.gz" || rm -f "epss/epss_scores-$day.csv.gz"
+	done
+
+	last=$(date -u -d "-$((keep - 1)) day" +'%Y-%m-%d')
+
+	for f in epss/epss_scores-*.csv.gz; do
+		[[ -f $f && $(basename $f) < "epss_scores-$last" ]] && rm -f $f
 	done
+fi
 
-	echo -e "\e[32mProcessing merged CVE database...\e[39m"
+if [[ -z $1 || $1 == "edb" ]]; then
+	echo -e "\e[32mDownloading Exploit-DB list...\e[39m"
 
-	awk -i inplace '/^\s*<\/nvd><\?xml/{next} NR>3&&/^\s*<nvd/{next} //{print $0}' cve-items.xml
+	rm -f exploitdb.csv
+	wget https://gitlab.com/exploit-database/exploitdb/-/raw/main/files_exploits.csv -O exploitdb.csv
+fi
+
+if [[ -z $1 || $1 == "msf" ]]; then
+	echo -e "\e[32mDownloading Metasploit modules...\e[39m"
+
+	rm -f msf-modules.json
+	wget https://raw.githubusercontent.com/rapid7/metasploit-framework/master/db/modules_metadata_base.json -O msf-modules.json
+fi
+
+if [[ $1 == "cveupd" ]]; then
+	for i in modified recent; do
+		echo -e "\e[32mDownloading CVE changes ($i)...\e[39m"
+		rm -f "nvdcve-2.0-$i.json.gz"
+		wget "https://nvd.nist.gov/feeds/json/cve/2.0/nvdcve-2.0-$i.json.gz" -O "nvdcve-2.0-$i.json.gz"
+	done
 fi
\ No newline at end of file
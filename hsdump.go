@@ -0,0 +1,125 @@
+package main
+
+import (
+	"io"
+	"os"
+	"fmt"
+	"bufio"
+	"reflect"
+	"math/bits"
+	"encoding/json"
+
+	"github.com/RoliSoft/Host-Scanner-Scripts/hsformat"
+)
+
+var entries interface{}
+
+// Reads the specified package file and decodes its entries based on
+// the package type found in the header.
+func parseInput(file string) error {
+	var err error
+	var fp  io.ReadCloser
+	var typ uint16
+
+	if fp, err = hsformat.Open(file); err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	typ, entries, err = hsformat.Read(fp)
+
+	if err == hsformat.ErrNoTerminator {
+		println("Warning: missing or invalid package terminator.")
+		err = nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// type is printed in the byte order used by the README
+	println(fmt.Sprintf("Package type 0x%04X, %d entries.", bits.ReverseBytes16(typ), reflect.ValueOf(entries).Len()))
+
+	return err
+}
+
+// Writes the decoded entries to the specified file, or to the standard
+// output if no file was specified.
+func serializeEntries(file string, ndjson bool) error {
+	var err error
+	var fp  *os.File
+
+	if len(file) == 0 {
+		fp = os.Stdout
+	} else {
+		if fp, err = os.Create(file); err != nil {
+			return err
+		}
+
+		defer fp.Close()
+	}
+
+	bw := bufio.NewWriter(fp)
+
+	defer bw.Flush()
+
+	if !ndjson {
+		var bs []byte
+		if bs, err = json.MarshalIndent(entries, "", "\t"); err != nil {
+			return err
+		}
+
+		bw.Write(bs)
+		bw.WriteString("\n")
+
+		return err
+	}
+
+	lst := reflect.ValueOf(entries)
+
+	for i := 0; i < lst.Len(); i++ {
+		var bs []byte
+		if bs, err = json.Marshal(lst.Index(i).Interface()); err != nil {
+			return err
+		}
+
+		bw.Write(bs)
+		bw.WriteString("\n")
+	}
+
+	return err
+}
+
+// Entry point of the application.
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "--ndjson" && len(os.Args) < 3 {
+		println("usage: hsdump [--ndjson] input [output]")
+		os.Exit(-1)
+	}
+
+	var err error
+	var ndj bool
+	var out string
+
+	if os.Args[1] == "--ndjson" {
+		ndj = true
+		os.Args = os.Args[1:]
+	}
+
+	if len(os.Args) > 2 {
+		out = os.Args[2]
+	}
+
+	println("Decoding package...")
+
+	if err = parseInput(os.Args[1]); err != nil {
+		println(err.Error())
+		os.Exit(-1)
+	}
+
+	if err = serializeEntries(out, ndj); err != nil {
+		println(err.Error())
+		os.Exit(-1)
+	}
+}
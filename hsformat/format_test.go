@@ -0,0 +1,164 @@
+package hsformat
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func must(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCPEDictionary(t *testing.T) {
+	entries := []*CPEEntry {
+		{
+			CPE:    "o:linux:linux_kernel",
+			Tokens: []string { "linux", "kernel" },
+			Versions: []*CPEVersion {
+				{ CPE: "3.10.0::~~~~arm64~", Version: "3.10.0", Tokens: []string { "on", "arm64", "architecture" } },
+				{ CPE: "4.4", Version: "4.4" },
+			},
+		},
+		{
+			CPE:    "a:gnu:g++",
+			Tokens: []string { "g++", "(gcc)", `c:\mingw`, "[x86]" },
+			Versions: []*CPEVersion {
+				{ CPE: "4.8.1", Version: "4.8.1", Tokens: []string { ".net", "^1.*$", "a|b" } },
+			},
+		},
+		{ CPE: "a:nginx:nginx", Tokens: []string { "nginx" } },
+	}
+
+	var buf bytes.Buffer
+	must(t, WriteCPEDictionary(&buf, entries))
+
+	// the tokens are stored escaped for use within a regular expression
+
+	for _, quoted := range []string { `g\+\+`, `\(gcc\)`, `c:\\mingw`, `\[x86\]`, `\.net`, `\^1\.\*\$`, `a\|b` } {
+		if !bytes.Contains(buf.Bytes(), []byte(quoted)) {
+			t.Errorf("WriteCPEDictionary() did not escape token as %s", quoted)
+		}
+	}
+
+	got, err := ReadCPEDictionary(&buf)
+	must(t, err)
+
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("ReadCPEDictionary() = %#v, want %#v", got, entries)
+	}
+}
+
+func TestCPEAliases(t *testing.T) {
+	entries := [][]string {
+		{ "a:nginx:nginx", "a:igor_sysoev:nginx" },
+		{ "a:mozilla:firefox", "a:mozilla:iceweasel", "a:mozilla:firefox_esr" },
+	}
+
+	var buf bytes.Buffer
+	must(t, WriteCPEAliases(&buf, entries))
+
+	got, err := ReadCPEAliases(&buf)
+	must(t, err)
+
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("ReadCPEAliases() = %#v, want %#v", got, entries)
+	}
+}
+
+func TestPayloads(t *testing.T) {
+	entries := []Payload {
+		{ Ports: []int { 53, 5353 }, Data: "\x00\x00\x10\x00\x00\x00\x00\x00\x00\x00\x00\x00" },
+		{ Ports: []int { 161 }, Data: "0\x82\x00/\x02\x01\x00\x04\x06public" },
+	}
+
+	var buf bytes.Buffer
+	must(t, WritePayloads(&buf, entries))
+
+	got, err := ReadPayloads(&buf)
+	must(t, err)
+
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("ReadPayloads() = %#v, want %#v", got, entries)
+	}
+}
+
+func TestServiceRegexes(t *testing.T) {
+	entries := []ServiceRegex {
+		{ Regex: `^SSH-2\.0-OpenSSH_([\w._-]+)`, CPE: "a:openbsd:openssh", Product: "OpenSSH", Version: "$1" },
+		{ Regex: `^Server: nginx`, CPE: "a:nginx:nginx" },
+	}
+
+	var buf bytes.Buffer
+	must(t, WriteServiceRegexes(&buf, entries))
+
+	got, err := ReadServiceRegexes(&buf)
+	must(t, err)
+
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("ReadServiceRegexes() = %#v, want %#v", got, entries)
+	}
+}
+
+func TestRead(t *testing.T) {
+	entries := [][]string { { "a:nginx:nginx", "a:igor_sysoev:nginx" } }
+
+	var buf bytes.Buffer
+	must(t, WriteCPEAliases(&buf, entries))
+
+	typ, got, err := Read(&buf)
+	must(t, err)
+
+	if typ != CPEAliases || !reflect.DeepEqual(got, entries) {
+		t.Errorf("Read() = %#x, %#v, want %#x, %#v", typ, got, CPEAliases, entries)
+	}
+}
+
+func TestPackageType(t *testing.T) {
+	var buf bytes.Buffer
+	must(t, WriteCPEAliases(&buf, [][]string { { "a:x:y", "a:z:y" } }))
+
+	if _, err := ReadPayloads(&buf); err != ErrPackageType {
+		t.Errorf("ReadPayloads() error = %v, want %v", err, ErrPackageType)
+	}
+}
+
+func TestPackageVersion(t *testing.T) {
+	var buf bytes.Buffer
+	hw := NewWriter(&buf)
+
+	must(t, hw.WriteHeader(CPEAliases, Version + 1, 0))
+	must(t, hw.Close())
+
+	if _, err := ReadCPEAliases(&buf); err != ErrPackageVersion {
+		t.Errorf("ReadCPEAliases() error = %v, want %v", err, ErrPackageVersion)
+	}
+}
+
+func TestNoTerminator(t *testing.T) {
+	var buf bytes.Buffer
+	must(t, WriteServiceRegexes(&buf, []ServiceRegex { { Regex: "^x", CPE: "a:x:y" } }))
+	buf.Truncate(buf.Len() - 4)
+
+	if _, err := ReadServiceRegexes(&buf); err != ErrNoTerminator {
+		t.Errorf("ReadServiceRegexes() error = %v, want %v", err, ErrNoTerminator)
+	}
+}
+
+func TestStringTooLong(t *testing.T) {
+	var buf bytes.Buffer
+	hw := NewWriter(&buf)
+
+	if err := hw.WriteString(strings.Repeat("x", 0xFFFF)); err != nil {
+		t.Errorf("WriteString() of 65535 bytes error = %v, want nil", err)
+	}
+
+	if err := hw.WriteString(strings.Repeat("x", 0x10000)); err != ErrStringTooLong {
+		t.Errorf("WriteString() of 65536 bytes error = %v, want %v", err, ErrStringTooLong)
+	}
+}
@@ -0,0 +1,19 @@
+package hsformat
+
+import (
+	"os"
+	"encoding/json"
+)
+
+// WriteJSON writes the specified entries to the file as indented JSON, which
+// the converters write instead of their package or database when the `--json`
+// argument is specified, in order to inspect the parsed data.
+func WriteJSON(file string, v interface{}) error {
+	bs, err := json.MarshalIndent(v, "", "\t")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, bs, 0644)
+}
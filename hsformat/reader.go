@@ -0,0 +1,475 @@
+package hsformat
+
+import (
+	"io"
+	"os"
+	"bufio"
+	"errors"
+	"strings"
+	"compress/gzip"
+	"compress/bzip2"
+	"encoding/binary"
+)
+
+var (
+	// ErrPackageType is returned when the header holds an unexpected package type.
+	ErrPackageType = errors.New("hsformat: unsupported package type")
+	// ErrPackageVersion is returned when the header holds an unsupported package version.
+	ErrPackageVersion = errors.New("hsformat: unsupported package version")
+	// ErrNoTerminator is returned when the entries are not followed by the package terminator.
+	ErrNoTerminator = errors.New("hsformat: missing or invalid package terminator")
+)
+
+// CPEEntry is an entry of the CPE dictionary package. The CPE names have no
+// `cpe:/` prefix, and the tokens are unescaped, while the package stores them
+// escaped for use within a regular expression.
+type CPEEntry struct {
+	CPE string
+	Tokens []string
+	Versions []*CPEVersion
+}
+
+// CPEVersion is a version-specific subentry of a CPEEntry.
+type CPEVersion struct {
+	CPE, Version string
+	Tokens []string
+}
+
+// Payload is an entry of the UDP payloads package.
+type Payload struct {
+	Ports []int
+	Data string
+}
+
+// ServiceRegex is an entry of the service regexes package.
+type ServiceRegex struct {
+	Regex, CPE, Product, Version string
+}
+
+// Reader reads little endian encoded packages from an underlying stream.
+type Reader struct {
+	br *bufio.Reader
+}
+
+// NewReader returns a buffered package reader for the specified stream.
+func NewReader(r io.Reader) *Reader {
+	return &Reader { br: bufio.NewReader(r) }
+}
+
+// Open opens the specified package file and transparently decompresses
+// it, if it starts with a gzip or bzip2 signature.
+func Open(file string) (io.ReadCloser, error) {
+	fp, err := os.Open(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(fp)
+	sig, _ := br.Peek(3)
+
+	switch {
+	case len(sig) >= 2 && sig[0] == 0x1f && sig[1] == 0x8b:
+		gz, err := gzip.NewReader(br)
+
+		if err != nil {
+			fp.Close()
+			return nil, err
+		}
+
+		return &readCloser { gz, fp }, nil
+	case len(sig) == 3 && string(sig) == "BZh":
+		return &readCloser { bzip2.NewReader(br), fp }, nil
+	}
+
+	return &readCloser { br, fp }, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// ReadHeader reads the package type, version and number of entries.
+func (r *Reader) ReadHeader() (typ, ver uint16, count int, err error) {
+	if err = binary.Read(r.br, binary.LittleEndian, &typ); err != nil {
+		return
+	}
+
+	if err = binary.Read(r.br, binary.LittleEndian, &ver); err != nil {
+		return
+	}
+
+	count, err = r.ReadUint32()
+
+	return
+}
+
+// ReadUint8 reads a single byte.
+func (r *Reader) ReadUint8() (int, error) {
+	v, err := r.br.ReadByte()
+
+	return int(v), err
+}
+
+// ReadUint16 reads a little endian uint16.
+func (r *Reader) ReadUint16() (int, error) {
+	var v uint16
+	err := binary.Read(r.br, binary.LittleEndian, &v)
+
+	return int(v), err
+}
+
+// ReadUint32 reads a little endian uint32.
+func (r *Reader) ReadUint32() (int, error) {
+	var v uint32
+	err := binary.Read(r.br, binary.LittleEndian, &v)
+
+	return int(v), err
+}
+
+// ReadString reads a string with a leading uint16 length.
+func (r *Reader) ReadString() (string, error) {
+	n, err := r.ReadUint16()
+
+	if err != nil {
+		return "", err
+	}
+
+	bs := make([]byte, n)
+
+	if _, err = io.ReadFull(r.br, bs); err != nil {
+		return "", err
+	}
+
+	return string(bs), nil
+}
+
+// ReadStrings8 reads a list of strings with a leading uint8 count.
+func (r *Reader) ReadStrings8() ([]string, error) {
+	n, err := r.ReadUint8()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.readStrings(n)
+}
+
+// ReadStrings16 reads a list of strings with a leading uint16 count.
+func (r *Reader) ReadStrings16() ([]string, error) {
+	n, err := r.ReadUint16()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.readStrings(n)
+}
+
+// ReadUint16s reads a list of uint16 values with a leading uint16 count.
+func (r *Reader) ReadUint16s() ([]int, error) {
+	n, err := r.ReadUint16()
+
+	if err != nil || n == 0 {
+		return nil, err
+	}
+
+	lst := make([]int, n)
+
+	for i := range lst {
+		if lst[i], err = r.ReadUint16(); err != nil {
+			return nil, err
+		}
+	}
+
+	return lst, nil
+}
+
+func (r *Reader) readStrings(n int) ([]string, error) {
+	var err error
+
+	if n == 0 {
+		return nil, nil
+	}
+
+	lst := make([]string, n)
+
+	for i := range lst {
+		if lst[i], err = r.ReadString(); err != nil {
+			return nil, err
+		}
+	}
+
+	return lst, nil
+}
+
+// ReadTerminator reads the package terminator following the last entry.
+func (r *Reader) ReadTerminator() error {
+	if end, err := r.ReadUint32(); err != nil || end != 0 {
+		return ErrNoTerminator
+	}
+
+	return nil
+}
+
+// Read decodes a package of any supported type from the specified stream.
+// The returned entries are of type []*CPEEntry, [][]string, []Payload or
+// []ServiceRegex, depending on the returned package type. If only the
+// terminator is missing, the entries are returned along with ErrNoTerminator.
+func Read(rd io.Reader) (uint16, interface{}, error) {
+	r := NewReader(rd)
+
+	typ, ver, count, err := r.ReadHeader()
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if ver != Version {
+		return typ, nil, ErrPackageVersion
+	}
+
+	var entries interface{}
+
+	switch typ {
+	case CPEDictionary:
+		entries, err = r.readCPEDictionary(count)
+	case CPEAliases:
+		entries, err = r.readCPEAliases(count)
+	case UDPPayloads:
+		entries, err = r.readPayloads(count)
+	case ServiceRegexes:
+		entries, err = r.readServiceRegexes(count)
+	default:
+		return typ, nil, ErrPackageType
+	}
+
+	if err != nil {
+		return typ, nil, err
+	}
+
+	return typ, entries, r.ReadTerminator()
+}
+
+// ReadCPEDictionary decodes a CPE dictionary package.
+func ReadCPEDictionary(rd io.Reader) ([]*CPEEntry, error) {
+	r := NewReader(rd)
+
+	count, err := r.expectHeader(CPEDictionary)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := r.readCPEDictionary(count)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, r.ReadTerminator()
+}
+
+// ReadCPEAliases decodes a CPE aliases package.
+func ReadCPEAliases(rd io.Reader) ([][]string, error) {
+	r := NewReader(rd)
+
+	count, err := r.expectHeader(CPEAliases)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := r.readCPEAliases(count)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, r.ReadTerminator()
+}
+
+// ReadPayloads decodes a UDP payloads package.
+func ReadPayloads(rd io.Reader) ([]Payload, error) {
+	r := NewReader(rd)
+
+	count, err := r.expectHeader(UDPPayloads)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := r.readPayloads(count)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, r.ReadTerminator()
+}
+
+// ReadServiceRegexes decodes a service regexes package.
+func ReadServiceRegexes(rd io.Reader) ([]ServiceRegex, error) {
+	r := NewReader(rd)
+
+	count, err := r.expectHeader(ServiceRegexes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := r.readServiceRegexes(count)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, r.ReadTerminator()
+}
+
+func (r *Reader) expectHeader(want uint16) (int, error) {
+	typ, ver, count, err := r.ReadHeader()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if typ != want {
+		return 0, ErrPackageType
+	}
+
+	if ver != Version {
+		return 0, ErrPackageVersion
+	}
+
+	return count, nil
+}
+
+func (r *Reader) readCPEDictionary(count int) ([]*CPEEntry, error) {
+	var err error
+	var entries []*CPEEntry
+
+	for i := 0; i < count; i++ {
+		entry := &CPEEntry { }
+
+		if entry.CPE, err = r.ReadString(); err != nil {
+			return nil, err
+		}
+
+		if entry.Tokens, err = r.ReadStrings8(); err != nil {
+			return nil, err
+		}
+
+		entry.Tokens = unquoteTokens(entry.Tokens)
+
+		var vers int
+		if vers, err = r.ReadUint32(); err != nil {
+			return nil, err
+		}
+
+		for j := 0; j < vers; j++ {
+			ver := &CPEVersion { }
+
+			if ver.CPE, err = r.ReadString(); err != nil {
+				return nil, err
+			}
+
+			if ver.Version, err = r.ReadString(); err != nil {
+				return nil, err
+			}
+
+			if ver.Tokens, err = r.ReadStrings8(); err != nil {
+				return nil, err
+			}
+
+			ver.Tokens = unquoteTokens(ver.Tokens)
+
+			entry.Versions = append(entry.Versions, ver)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Reverses the escaping of the tokens by regexp.QuoteMeta, which prefixes the
+// metacharacters with a backslash.
+func unquoteTokens(tokens []string) []string {
+	for i, token := range tokens {
+		if !strings.Contains(token, `\`) {
+			continue
+		}
+
+		buf := make([]byte, 0, len(token))
+
+		for j := 0; j < len(token); j++ {
+			if token[j] == '\\' && j + 1 < len(token) {
+				j++
+			}
+
+			buf = append(buf, token[j])
+		}
+
+		tokens[i] = string(buf)
+	}
+
+	return tokens
+}
+
+func (r *Reader) readCPEAliases(count int) ([][]string, error) {
+	var entries [][]string
+
+	for i := 0; i < count; i++ {
+		entry, err := r.ReadStrings16()
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (r *Reader) readPayloads(count int) ([]Payload, error) {
+	var err error
+	var entries []Payload
+
+	for i := 0; i < count; i++ {
+		entry := Payload { }
+
+		if entry.Data, err = r.ReadString(); err != nil {
+			return nil, err
+		}
+
+		if entry.Ports, err = r.ReadUint16s(); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (r *Reader) readServiceRegexes(count int) ([]ServiceRegex, error) {
+	var entries []ServiceRegex
+
+	for i := 0; i < count; i++ {
+		var err error
+		var fields [4]string
+
+		for j := range fields {
+			if fields[j], err = r.ReadString(); err != nil {
+				return nil, err
+			}
+		}
+
+		entries = append(entries, ServiceRegex { fields[0], fields[1], fields[2], fields[3] })
+	}
+
+	return entries, nil
+}
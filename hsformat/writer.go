@@ -0,0 +1,268 @@
+// Package hsformat implements the generic binary package format used by
+// the data files of Host Scanner.
+package hsformat
+
+import (
+	"io"
+	"bufio"
+	"errors"
+	"regexp"
+	"encoding/binary"
+)
+
+// Package types, as stored in the header.
+const (
+	CPEDictionary  uint16 = 0x0001
+	CPEAliases     uint16 = 0x0002
+	UDPPayloads    uint16 = 0x000A
+	ServiceRegexes uint16 = 0x000F
+)
+
+// Version of the package layouts currently produced by the converters.
+const Version uint16 = 0x0001
+
+var (
+	// ErrStringTooLong is returned when a string does not fit its uint16 length prefix.
+	ErrStringTooLong = errors.New("hsformat: string longer than 65535 bytes")
+	// ErrOutOfRange is returned when a number or list length does not fit its field.
+	ErrOutOfRange = errors.New("hsformat: value out of range for field")
+)
+
+// Writer writes little endian encoded packages to an underlying stream.
+type Writer struct {
+	bw *bufio.Writer
+}
+
+// NewWriter returns a buffered package writer for the specified stream.
+// Close must be called after the last entry, in order to write the
+// terminator and flush the buffer.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer { bw: bufio.NewWriter(w) }
+}
+
+// WriteHeader writes the package type, version and number of entries.
+func (w *Writer) WriteHeader(typ, ver uint16, count int) error {
+	if err := binary.Write(w.bw, binary.LittleEndian, typ); err != nil {
+		return err
+	}
+
+	if err := binary.Write(w.bw, binary.LittleEndian, ver); err != nil {
+		return err
+	}
+
+	return w.WriteUint32(count)
+}
+
+// WriteUint8 writes a single byte.
+func (w *Writer) WriteUint8(v int) error {
+	if v < 0 || v > 0xFF {
+		return ErrOutOfRange
+	}
+
+	return w.bw.WriteByte(uint8(v))
+}
+
+// WriteUint16 writes a little endian uint16.
+func (w *Writer) WriteUint16(v int) error {
+	if v < 0 || v > 0xFFFF {
+		return ErrOutOfRange
+	}
+
+	return binary.Write(w.bw, binary.LittleEndian, uint16(v))
+}
+
+// WriteUint32 writes a little endian uint32.
+func (w *Writer) WriteUint32(v int) error {
+	if v < 0 || int64(v) > 0xFFFFFFFF {
+		return ErrOutOfRange
+	}
+
+	return binary.Write(w.bw, binary.LittleEndian, uint32(v))
+}
+
+// WriteString writes a string with a leading uint16 length and no trailing NULL.
+func (w *Writer) WriteString(s string) error {
+	if len(s) > 0xFFFF {
+		return ErrStringTooLong
+	}
+
+	if err := binary.Write(w.bw, binary.LittleEndian, uint16(len(s))); err != nil {
+		return err
+	}
+
+	_, err := w.bw.WriteString(s)
+
+	return err
+}
+
+// WriteStrings8 writes a list of strings with a leading uint8 count.
+func (w *Writer) WriteStrings8(lst []string) error {
+	if err := w.WriteUint8(len(lst)); err != nil {
+		return err
+	}
+
+	return w.writeStrings(lst)
+}
+
+// WriteStrings16 writes a list of strings with a leading uint16 count.
+func (w *Writer) WriteStrings16(lst []string) error {
+	if err := w.WriteUint16(len(lst)); err != nil {
+		return err
+	}
+
+	return w.writeStrings(lst)
+}
+
+// WriteUint16s writes a list of uint16 values with a leading uint16 count.
+func (w *Writer) WriteUint16s(lst []int) error {
+	if err := w.WriteUint16(len(lst)); err != nil {
+		return err
+	}
+
+	for _, v := range lst {
+		if err := w.WriteUint16(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) writeStrings(lst []string) error {
+	for _, s := range lst {
+		if err := w.WriteString(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close writes the package terminator and flushes the buffered data.
+// The underlying stream is not closed.
+func (w *Writer) Close() error {
+	if err := binary.Write(w.bw, binary.LittleEndian, uint32(0)); err != nil {
+		return err
+	}
+
+	return w.bw.Flush()
+}
+
+// WriteCPEDictionary encodes a CPE dictionary package. The CPE names are
+// written as they are, while the tokens are escaped for use within a regular
+// expression, which ReadCPEDictionary reverses.
+func WriteCPEDictionary(wr io.Writer, entries []*CPEEntry) error {
+	w := NewWriter(wr)
+
+	if err := w.WriteHeader(CPEDictionary, Version, len(entries)); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		// CPE: o:linux:linux_kernel
+		if err := w.WriteString(entry.CPE); err != nil {
+			return err
+		}
+
+		// tokens: Linux, Kernel
+		if err := w.WriteStrings8(quoteTokens(entry.Tokens)); err != nil {
+			return err
+		}
+
+		// number of versions
+		if err := w.WriteUint32(len(entry.Versions)); err != nil {
+			return err
+		}
+
+		for _, ver := range entry.Versions {
+			// CPE: 3.10.0::~~~~arm64~
+			if err := w.WriteString(ver.CPE); err != nil {
+				return err
+			}
+
+			// version: 3.10.0
+			if err := w.WriteString(ver.Version); err != nil {
+				return err
+			}
+
+			// tokens: on, ARM64, architecture
+			if err := w.WriteStrings8(quoteTokens(ver.Tokens)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return w.Close()
+}
+
+// WriteCPEAliases encodes a CPE aliases package.
+func WriteCPEAliases(wr io.Writer, entries [][]string) error {
+	w := NewWriter(wr)
+
+	if err := w.WriteHeader(CPEAliases, Version, len(entries)); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		// aliases in entry
+		if err := w.WriteStrings16(entry); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+// WritePayloads encodes a UDP payloads package.
+func WritePayloads(wr io.Writer, entries []Payload) error {
+	w := NewWriter(wr)
+
+	if err := w.WriteHeader(UDPPayloads, Version, len(entries)); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		// payload data
+		if err := w.WriteString(entry.Data); err != nil {
+			return err
+		}
+
+		// ports in entry
+		if err := w.WriteUint16s(entry.Ports); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+// WriteServiceRegexes encodes a service regexes package.
+func WriteServiceRegexes(wr io.Writer, entries []ServiceRegex) error {
+	w := NewWriter(wr)
+
+	if err := w.WriteHeader(ServiceRegexes, Version, len(entries)); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		// regex, cpe, product, version
+		for _, field := range []string { entry.Regex, entry.CPE, entry.Product, entry.Version } {
+			if err := w.WriteString(field); err != nil {
+				return err
+			}
+		}
+	}
+
+	return w.Close()
+}
+
+// Escapes the specified tokens for use within a regular expression.
+func quoteTokens(tokens []string) []string {
+	quoted := make([]string, len(tokens))
+
+	for i, token := range tokens {
+		quoted[i] = regexp.QuoteMeta(token)
+	}
+
+	return quoted
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+	"bufio"
+	"errors"
+	"strings"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/RoliSoft/Host-Scanner-Scripts/cvedb"
+	"github.com/RoliSoft/Host-Scanner-Scripts/hsformat"
+)
+
+var entries catalog
+
+type catalog struct {
+	Title string `json:"title"`
+	Version string `json:"catalogVersion"`
+	Released string `json:"dateReleased"`
+	Items []item `json:"vulnerabilities"`
+}
+
+type item struct {
+	CVE string `json:"cveID"`
+	Vendor string `json:"vendorProject"`
+	Product string `json:"product"`
+	Name string `json:"vulnerabilityName"`
+	DateAdded string `json:"dateAdded"`
+	Description string `json:"shortDescription"`
+	RequiredAction string `json:"requiredAction"`
+	DueDate string `json:"dueDate"`
+	Ransomware string `json:"knownRansomwareCampaignUse"`
+	Notes string `json:"notes"`
+}
+
+// Reads the specified KEV catalog, either the JSON or the CSV edition,
+// optionally compressed.
+func parseInput(file string) error {
+	var err error
+	var fp  io.ReadCloser
+
+	if fp, err = hsformat.Open(file); err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	if strings.Contains(strings.ToLower(file), ".csv") {
+		return parseCSV(fp)
+	}
+
+	return json.NewDecoder(bufio.NewReader(fp)).Decode(&entries)
+}
+
+// Reads the CSV edition of the catalog, whose columns are named after the
+// fields of the JSON edition. The CSV edition has no catalog version.
+func parseCSV(rd io.Reader) error {
+	var err error
+	var row []string
+
+	cr := csv.NewReader(bufio.NewReader(rd))
+	cr.FieldsPerRecord = -1
+
+	if row, err = cr.Read(); err != nil {
+		return err
+	}
+
+	cols := make(map[string]int)
+
+	for i, name := range row {
+		cols[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+
+	if _, ok := cols["cveID"]; !ok {
+		return errors.New("no cveID column in catalog")
+	}
+
+	col := func(row []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+
+		return ""
+	}
+
+	for {
+		if row, err = cr.Read(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+
+			break
+		}
+
+		entries.Items = append(entries.Items, item {
+			CVE:            col(row, "cveID"),
+			Vendor:         col(row, "vendorProject"),
+			Product:        col(row, "product"),
+			Name:           col(row, "vulnerabilityName"),
+			DateAdded:      col(row, "dateAdded"),
+			Description:    col(row, "shortDescription"),
+			RequiredAction: col(row, "requiredAction"),
+			DueDate:        col(row, "dueDate"),
+			Ransomware:     col(row, "knownRansomwareCampaignUse"),
+			Notes:          col(row, "notes"),
+		})
+	}
+
+	return err
+}
+
+// Writes the globally loaded entries to the `kev` table of the specified CVE
+// database, replacing the ones imported previously.
+func serializeEntries(file string, debug bool) error {
+	var err error
+
+	if debug {
+		return hsformat.WriteJSON(file, entries)
+	}
+
+	var db *sql.DB
+	var tx *sql.Tx
+
+	stmts := []string {
+		`create table if not exists kev (vuln_id int not null, cve text, vendor text, product text, name text, description text, date_added int, due_date int, ransomware boolean, required_action text, notes text, primary key(vuln_id))`,
+	}
+
+	if db, err = cvedb.OpenDatabase(file, stmts...); err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	if tx, err = db.Begin(); err != nil {
+		return err
+	}
+
+	for _, stmt := range []string { `delete from kev`, `delete from meta where key like 'kev\_%' escape '\'` } {
+		if _, err = tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	var stm *sql.Stmt
+
+	if stm, err = tx.Prepare(`insert or replace into kev values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, entry := range entries.Items {
+		var id int64
+
+		if id, err = cvedb.Key(entry.CVE); err != nil {
+			println("Skipping " + entry.CVE + ": " + err.Error())
+			continue
+		}
+
+		ransomware := strings.EqualFold(entry.Ransomware, "Known")
+
+		if _, err = stm.Exec(id, strings.TrimPrefix(entry.CVE, "CVE-"), entry.Vendor, entry.Product, entry.Name, entry.Description, cvedb.UnixDate(entry.DateAdded), cvedb.UnixDate(entry.DueDate), ransomware, entry.RequiredAction, entry.Notes); err != nil {
+			stm.Close()
+			tx.Rollback()
+			return err
+		}
+	}
+
+	stm.Close()
+
+	meta := map[string]string {
+		"kev_version":  entries.Version,
+		"kev_released": entries.Released,
+		"kev_imported": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	for key, value := range meta {
+		if len(value) == 0 {
+			continue
+		}
+
+		if _, err = tx.Exec(`insert or replace into meta values (?, ?)`, key, value); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Entry point of the application.
+func main() {
+	if len(os.Args) < 3 {
+		println("usage: kev2hs [--json] input output")
+		os.Exit(-1)
+	}
+
+	var err error
+	var dbg bool
+
+	if os.Args[1] == "--json" {
+		dbg = true
+		os.Args = os.Args[1:]
+	}
+
+	println("Parsing KEV catalog...")
+
+	if err = parseInput(os.Args[1]); err != nil {
+		println(err.Error())
+		os.Exit(-1)
+	}
+
+	println("Writing parsed data...")
+
+	if err = serializeEntries(os.Args[2], dbg); err != nil {
+		println(err.Error())
+		os.Exit(-1)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sort"
+	"time"
+	"bufio"
+	"strconv"
+	"strings"
+	"database/sql"
+	"encoding/json"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/RoliSoft/Host-Scanner-Scripts/cvedb"
+	"github.com/RoliSoft/Host-Scanner-Scripts/hsformat"
+)
+
+var entries []*item
+
+type item struct {
+	Name string `json:"name"`
+	Path string `json:"fullname"`
+	Type string `json:"type"`
+	Rank int `json:"rank"`
+	Disclosed string `json:"disclosure_date"`
+	References []string `json:"references"`
+	Targets []string `json:"targets"`
+	Stance string `json:"stance"`
+}
+
+// Reads the specified `modules_metadata_base.json` file of Metasploit,
+// optionally compressed, and keeps the modules with references.
+func parseInput(file string) error {
+	var err error
+	var fp  io.ReadCloser
+
+	if fp, err = hsformat.Open(file); err != nil {
+		return err
+	}
+
+	defer fp.Close()
+
+	var mods map[string]*item
+
+	if err = json.NewDecoder(bufio.NewReader(fp)).Decode(&mods); err != nil {
+		return err
+	}
+
+	for _, mod := range mods {
+		if len(mod.References) != 0 {
+			entries = append(entries, mod)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	return err
+}
+
+// Checks whether the module is an exploit attacking a remote service, as
+// opposed to a local privilege escalation or a passive client-side exploit.
+func (mod *item) remote() bool {
+	return mod.Type == "exploit" && !strings.Contains(mod.Path, "/local/") && !strings.EqualFold(mod.Stance, "passive")
+}
+
+// Returns the IDs of the vulnerabilities referencing the URLs of the modules,
+// which are looked up in a single pass over the `refs` table.
+func referencedURLs(db *sql.DB) (map[string][]int64, error) {
+	urls := make(map[string][]int64)
+
+	for _, mod := range entries {
+		for _, ref := range mod.References {
+			if strings.HasPrefix(ref, "URL-") {
+				urls[ref[4:]] = nil
+			}
+		}
+	}
+
+	rows, err := db.Query(`select vuln_id, url from refs`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id  int64
+		var url string
+
+		if err = rows.Scan(&id, &url); err != nil {
+			return nil, err
+		}
+
+		if ids, ok := urls[url]; ok {
+			urls[url] = append(ids, id)
+		}
+	}
+
+	return urls, rows.Err()
+}
+
+// Returns the IDs of the vulnerabilities of the Exploit-DB exploits written by
+// edb2hs, if any.
+func referencedExploits(db *sql.DB) (map[int][]int64, error) {
+	edbs := make(map[int][]int64)
+
+	var found bool
+	db.QueryRow(`select count(*) > 0 from sqlite_master where type = 'table' and name = 'exploits'`).Scan(&found)
+
+	if !found {
+		return edbs, nil
+	}
+
+	rows, err := db.Query(`select vuln_id, edb_id from exploits where source = 'exploit-db'`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id  int64
+		var edb int
+
+		if err = rows.Scan(&id, &edb); err != nil {
+			return nil, err
+		}
+
+		edbs[edb] = append(edbs[edb], id)
+	}
+
+	return edbs, rows.Err()
+}
+
+// Writes the globally loaded entries to the `msf_modules` table of the
+// specified CVE database, replacing the ones imported previously. The modules
+// are linked to the vulnerabilities by their CVE references, or through their
+// Exploit-DB and URL references if the vulnerabilities also reference them.
+func serializeEntries(file string, debug bool) error {
+	var err error
+
+	if debug {
+		return hsformat.WriteJSON(file, entries)
+	}
+
+	var db *sql.DB
+	var tx *sql.Tx
+
+	stmts := []string {
+		`create table if not exists msf_modules (vuln_id int not null, path text, name text, type text, rank int, targets text, disclosure_date int, remote boolean, reference text)`,
+		`create index if not exists msf_vuln_idx on msf_modules (vuln_id)`,
+		`create view if not exists msf_remote as select distinct vuln_id from msf_modules where remote`,
+	}
+
+	if db, err = cvedb.OpenDatabase(file, stmts...); err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	var urls map[string][]int64
+	var edbs map[int][]int64
+
+	if urls, err = referencedURLs(db); err != nil {
+		return err
+	}
+
+	if edbs, err = referencedExploits(db); err != nil {
+		return err
+	}
+
+	if tx, err = db.Begin(); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(`delete from msf_modules`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var stm *sql.Stmt
+
+	if stm, err = tx.Prepare(`insert into msf_modules values (?, ?, ?, ?, ?, ?, ?, ?, ?)`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, mod := range entries {
+		var ids  []int64
+		var refs []string
+
+		// CVE references take precedence over the other ones linking the
+		// module to the same vulnerability
+
+		seen := make(map[int64]bool)
+
+		link := func(id int64, ref string) {
+			if !seen[id] {
+				seen[id] = true
+				ids  = append(ids, id)
+				refs = append(refs, ref)
+			}
+		}
+
+		for _, ref := range mod.References {
+			if strings.HasPrefix(ref, "CVE-") {
+				if id, err := cvedb.Key(ref); err == nil {
+					link(id, ref)
+				}
+			}
+		}
+
+		for _, ref := range mod.References {
+			switch {
+			case strings.HasPrefix(ref, "EDB-"):
+				edb, _ := strconv.Atoi(ref[4:])
+
+				for _, id := range edbs[edb] {
+					link(id, ref)
+				}
+			case strings.HasPrefix(ref, "URL-"):
+				for _, id := range urls[ref[4:]] {
+					link(id, ref)
+				}
+			}
+		}
+
+		for i, id := range ids {
+			if _, err = stm.Exec(id, mod.Path, mod.Name, mod.Type, mod.Rank, strings.Join(mod.Targets, "\n"), cvedb.UnixDate(mod.Disclosed), mod.remote(), refs[i]); err != nil {
+				stm.Close()
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	stm.Close()
+
+	if _, err = tx.Exec(`insert or replace into meta values ('msf_imported', ?)`, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Entry point of the application.
+func main() {
+	if len(os.Args) < 3 {
+		println("usage: msf2hs [--json] input output")
+		os.Exit(-1)
+	}
+
+	var err error
+	var dbg bool
+
+	if os.Args[1] == "--json" {
+		dbg = true
+		os.Args = os.Args[1:]
+	}
+
+	println("Parsing Metasploit modules...")
+
+	if err = parseInput(os.Args[1]); err != nil {
+		println(err.Error())
+		os.Exit(-1)
+	}
+
+	println("Writing parsed data...")
+
+	if err = serializeEntries(os.Args[2], dbg); err != nil {
+		println(err.Error())
+		os.Exit(-1)
+	}
+}
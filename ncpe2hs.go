@@ -2,18 +2,13 @@ package main
 
 import (
 	"os"
-	"bufio"
 	"regexp"
 	"io/ioutil"
-	"encoding/json"
-	"encoding/binary"
-)
 
-var entries []entry
+	"github.com/RoliSoft/Host-Scanner-Scripts/hsformat"
+)
 
-type entry struct {
-	Regex, CPE, Product, Version string
-}
+var entries []hsformat.ServiceRegex
 
 // Reads the specified file and sends the entries for processing.
 func parseInput(file string) error {
@@ -39,7 +34,7 @@ func parseInput(file string) error {
 	mc  = append(mc, rem3.FindAllStringSubmatch(dat, -1)...)
 
 	for _, m := range mc {
-		entry := entry {
+		entry := hsformat.ServiceRegex {
 			Regex: m[1],
 		}
 
@@ -71,54 +66,17 @@ func serializeEntries(file string, debug bool) error {
 	var err error
 	var fp  *os.File
 
-	if fp, err = os.Create(file); err != nil {
-		return err
-	}
-
-	defer fp.Close()
-
-	bw := bufio.NewWriter(fp)
-
 	if debug {
-		var bs []byte
-		bs, err = json.MarshalIndent(entries, "", "\t")
-
-		bw.Write(bs)
-		bw.Flush()
-
-		return err
+		return hsformat.WriteJSON(file, entries)
 	}
 
-	// package type: service regexes
-	binary.Write(bw, binary.LittleEndian, uint16(15))
-	// package version
-	binary.Write(bw, binary.LittleEndian, uint16(1))
-	// number of entries
-	binary.Write(bw, binary.LittleEndian, uint32(len(entries)))
-
-	for _, entry := range entries {
-		// regex
-		binary.Write(bw, binary.LittleEndian, uint16(len(entry.Regex)))
-		bw.WriteString(entry.Regex)
-
-		// cpe
-		binary.Write(bw, binary.LittleEndian, uint16(len(entry.CPE)))
-		bw.WriteString(entry.CPE)
-
-		// product
-		binary.Write(bw, binary.LittleEndian, uint16(len(entry.Product)))
-		bw.WriteString(entry.Product)
-
-		// version
-		binary.Write(bw, binary.LittleEndian, uint16(len(entry.Version)))
-		bw.WriteString(entry.Version)
+	if fp, err = os.Create(file); err != nil {
+		return err
 	}
 
-	binary.Write(bw, binary.LittleEndian, uint32(0))
-
-	bw.Flush()
+	defer fp.Close()
 
-	return err
+	return hsformat.WriteServiceRegexes(fp, entries)
 }
 
 // Entry point of the application.
@@ -139,14 +97,14 @@ func main() {
 	println("Parsing nmap service probes database...")
 
 	if err = parseInput(os.Args[1]); err != nil {
-		println(err)
+		println(err.Error())
 		os.Exit(-1)
 	}
 
 	println("Writing parsed data...")
 
 	if err = serializeEntries(os.Args[2], dbg); err != nil {
-		println(err)
+		println(err.Error())
 		os.Exit(-1)
 	}
 }
\ No newline at end of file
@@ -3,20 +3,14 @@ package main
 import (
 	"os"
 	"path"
-	"bufio"
 	"regexp"
 	"strconv"
 	"io/ioutil"
-	"encoding/json"
-	"encoding/binary"
-)
 
-var entries []entry
+	"github.com/RoliSoft/Host-Scanner-Scripts/hsformat"
+)
 
-type entry struct {
-	Ports []int
-	Data string
-}
+var entries []hsformat.Payload
 
 // Reads the specified files in the directory and sends the entries for processing.
 func parseInput(dir string) error {
@@ -35,7 +29,7 @@ func parseInput(dir string) error {
 			continue
 		}
 
-		entry := entry { }
+		entry := hsformat.Payload { }
 
 		if mc := repn.FindAllStringSubmatch(f.Name(), -1); len(mc) > 0 {
 			if i, e := strconv.Atoi(mc[0][1]); e == nil {
@@ -67,49 +61,17 @@ func serializeEntries(file string, debug bool) error {
 	var err error
 	var fp  *os.File
 
-	if fp, err = os.Create(file); err != nil {
-		return err
-	}
-
-	defer fp.Close()
-
-	bw := bufio.NewWriter(fp)
-
 	if debug {
-		var bs []byte
-		bs, err = json.MarshalIndent(entries, "", "\t")
-
-		bw.Write(bs)
-		bw.Flush()
-
-		return err
+		return hsformat.WriteJSON(file, entries)
 	}
 
-	// package type: UDP payloads
-	binary.Write(bw, binary.LittleEndian, uint16(10))
-	// package version
-	binary.Write(bw, binary.LittleEndian, uint16(1))
-	// number of entries
-	binary.Write(bw, binary.LittleEndian, uint32(len(entries)))
-
-	for _, entry := range entries {
-		// payload data
-		binary.Write(bw, binary.LittleEndian, uint16(len(entry.Data)))
-		bw.WriteString(entry.Data)
-
-		// number of ports in entry
-		binary.Write(bw, binary.LittleEndian, uint16(len(entry.Ports)))
-
-		for _, port := range entry.Ports {
-			binary.Write(bw, binary.LittleEndian, uint16(port))
-		}
+	if fp, err = os.Create(file); err != nil {
+		return err
 	}
 
-	binary.Write(bw, binary.LittleEndian, uint32(0))
-
-	bw.Flush()
+	defer fp.Close()
 
-	return err
+	return hsformat.WritePayloads(fp, entries)
 }
 
 // Entry point of the application.
@@ -130,14 +92,14 @@ func main() {
 	println("Parsing zmap payloads database...")
 
 	if err = parseInput(os.Args[1]); err != nil {
-		println(err)
+		println(err.Error())
 		os.Exit(-1)
 	}
 
 	println("Writing parsed data...")
 
 	if err = serializeEntries(os.Args[2], dbg); err != nil {
-		println(err)
+		println(err.Error())
 		os.Exit(-1)
 	}
 }
\ No newline at end of file